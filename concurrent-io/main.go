@@ -1,11 +1,13 @@
 package main
 
 import (
+	"config"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
+	"tracing"
 )
 
 func SendRequest[T any](client *http.Client, url string) (T, error) {
@@ -47,32 +49,43 @@ type Post struct {
 	Body   string `json:"body"`
 }
 
-func concurrently[T any](urls []string) string {
+func concurrently[T any](client *http.Client, urls []string) string {
 	ch1 := make(chan Result[T])
 	ch2 := make(chan Result[T])
-	go SendRequestWithChan(http.DefaultClient, urls[0], ch1)
-	go SendRequestWithChan(http.DefaultClient, urls[1], ch2)
+	go SendRequestWithChan(client, urls[0], ch1)
+	go SendRequestWithChan(client, urls[1], ch2)
 	fst, snd := <-ch1, <-ch2
 	return fmt.Sprintf("first result: %+v\n\n second result: %+v\n\n", fst, snd)
 }
 
-func sequentially[T any](urls []string) string {
-	p1, err := SendRequest[T](http.DefaultClient, urls[0])
+func sequentially[T any](client *http.Client, urls []string) string {
+	p1, err := SendRequest[T](client, urls[0])
 	fst := Result[T]{p1, err}
-	p2, err := SendRequest[T](http.DefaultClient, urls[1])
+	p2, err := SendRequest[T](client, urls[1])
 	snd := Result[T]{p2, err}
 	return fmt.Sprintf("first result: %+v\n\n second result: %+v\n\n", fst, snd)
 }
 
 func main() {
+	var tracingConfig tracing.Config
+	if err := config.Load(&tracingConfig); err != nil {
+		panic(err)
+	}
+	tracer, err := tracingConfig.NewTracer("concurrent-io")
+	if err != nil {
+		panic(err)
+	}
+	defer tracer.Close()
+	client := tracing.NewClient(tracer)
+
 	start := time.Now().UnixMilli()
 	urls := []string{
 		"https://jsonplaceholder.typicode.com/posts/1",
 		"https://jsonplaceholder.typicode.com/posts/2",
 	}
 
-	// report := sequentially[Post](urls)
-	report := concurrently[Post](urls)
+	// report := sequentially[Post](client, urls)
+	report := concurrently[Post](client, urls)
 
 	fmt.Println(report)
 	fmt.Printf("completed in %v milliseconds\n", time.Now().UnixMilli()-start)
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+type Validator interface {
+	Validate() error
+}
+
+type options struct {
+	envFiles    []string
+	envFilesSet bool
+	args        []string
+	lookup      func(string) (string, bool)
+}
+
+type Option func(*options)
+
+func WithEnvFiles(paths ...string) Option {
+	return func(o *options) {
+		o.envFiles = paths
+		o.envFilesSet = true
+	}
+}
+
+func WithArgs(args []string) Option {
+	return func(o *options) {
+		o.args = args
+	}
+}
+
+func WithLookup(lookup func(string) (string, bool)) Option {
+	return func(o *options) {
+		o.lookup = lookup
+	}
+}
+
+type setting struct {
+	env      string
+	value    reflect.Value
+	fallback string
+	required bool
+	usage    string
+}
+
+func Load(v any, opts ...Option) error {
+	o := options{args: os.Args[1:], lookup: os.LookupEnv}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !o.envFilesSet {
+		if path, ok := o.lookup("ENV_FILE"); ok && path != "" {
+			o.envFiles = []string{path}
+		} else {
+			o.envFiles = FindEnvFiles(".env")
+		}
+	}
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Load requires a pointer to a struct, not %T", v)
+	}
+	settings := collect(target.Elem())
+	files, err := readEnvFiles(o.envFiles, o.lookup)
+	if err != nil {
+		return err
+	}
+	flags, err := parseFlags(settings, o.args)
+	if err != nil {
+		return err
+	}
+	var errs Errors
+	for _, s := range settings {
+		value, ok := flags[s.env]
+		if !ok {
+			value, ok = o.lookup(s.env)
+		}
+		if !ok {
+			value, ok = files[s.env]
+		}
+		if !ok || value == "" {
+			value = s.fallback
+		}
+		if value == "" {
+			if s.required {
+				errs = append(errs, fmt.Errorf("%s is required", s.env))
+			}
+			continue
+		}
+		if err := set(s.value, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+		}
+	}
+	errs = append(errs, validate(target)...)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func collect(v reflect.Value) []setting {
+	var settings []setting
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		env, ok := field.Tag.Lookup("env")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				settings = append(settings, collect(v.Field(i))...)
+			}
+			continue
+		}
+		settings = append(settings, setting{
+			env:      env,
+			value:    v.Field(i),
+			fallback: field.Tag.Get("default"),
+			required: field.Tag.Get("required") == "true",
+			usage:    field.Tag.Get("usage"),
+		})
+	}
+	return settings
+}
+
+func validate(ptr reflect.Value) Errors {
+	var errs Errors
+	v := ptr.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if _, ok := field.Tag.Lookup("env"); !ok && field.IsExported() && field.Type.Kind() == reflect.Struct {
+			errs = append(errs, validate(v.Field(i).Addr())...)
+		}
+	}
+	validator, ok := ptr.Interface().(Validator)
+	if !ok {
+		return errs
+	}
+	err := validator.Validate()
+	var nested Errors
+	switch {
+	case err == nil:
+	case errors.As(err, &nested):
+		errs = append(errs, nested...)
+	default:
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+func parseFlags(settings []setting, args []string) (map[string]string, error) {
+	values := make(map[string]string)
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	for _, s := range settings {
+		env := s.env
+		usage := s.usage
+		if usage == "" {
+			usage = "overrides $" + env
+		}
+		if s.fallback != "" {
+			usage += " (default " + s.fallback + ")"
+		}
+		fs.Func(flagName(env), usage, func(value string) error {
+			values[env] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func set(v reflect.Value, value string) error {
+	if v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes([]byte(value))
+		return nil
+	case v.Kind() == reflect.Slice:
+		parts := strings.Split(value, ",")
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := set(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
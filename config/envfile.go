@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FindEnvFiles lists name in the working directory and each parent up to the
+// workspace root, or failing that the module root. Outside of either it
+// lists only the working directory's.
+func FindEnvFiles(name string) []string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	root := 0
+	if i := findRoot(dirs, "go.work"); i >= 0 {
+		root = i
+	} else if i := findRoot(dirs, "go.mod"); i >= 0 {
+		root = i
+	}
+	paths := make([]string, root+1)
+	for i := range paths {
+		paths[i] = filepath.Join(dirs[i], name)
+	}
+	return paths
+}
+
+func findRoot(dirs []string, marker string) int {
+	for i, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+func readEnvFiles(paths []string, lookup func(string) (string, bool)) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseEnv(f, func(key string) (string, bool) {
+			if value, ok := lookup(key); ok {
+				return value, true
+			}
+			value, ok := values[key]
+			return value, ok
+		})
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for key, value := range parsed {
+			if _, ok := values[key]; !ok {
+				values[key] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+func ParseEnv(r io.Reader, lookup func(string) (string, bool)) (map[string]string, error) {
+	values := make(map[string]string)
+	expand := func(key string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		value, _ := lookup(key)
+		return value
+	}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", n)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, "'"):
+			if len(value) < 2 || !strings.HasSuffix(value, "'") {
+				return nil, fmt.Errorf("line %d: unterminated quote", n)
+			}
+			value = value[1 : len(value)-1]
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value", n)
+			}
+			value = os.Expand(unquoted, expand)
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			value = os.Expand(value, expand)
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
@@ -1,10 +1,12 @@
 package json
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"requestid"
 )
 
 func NewFetcher[T any](client *http.Client, baseURL string) *fetcher[T] {
@@ -17,10 +19,14 @@ type fetcher[T any] struct {
 }
 
 func (f *fetcher[T]) FetchById(id string) (T, error) {
+	return f.FetchByIdContext(context.Background(), id)
+}
+
+func (f *fetcher[T]) FetchByIdContext(ctx context.Context, id string) (T, error) {
 	var result T
 	method := "GET"
 	url := f.baseURL + "/" + id
-	data, err := f.send(method, url)
+	data, err := f.send(ctx, method, url)
 	if err != nil {
 		return result, fmt.Errorf("could not %+v %+v - %w", method, url, err)
 	}
@@ -36,10 +42,14 @@ func (f *fetcher[T]) FetchById(id string) (T, error) {
 }
 
 func (f *fetcher[T]) FetchWhere(query string) ([]T, error) {
+	return f.FetchWhereContext(context.Background(), query)
+}
+
+func (f *fetcher[T]) FetchWhereContext(ctx context.Context, query string) ([]T, error) {
 	var result []T
 	method := "GET"
 	url := f.baseURL + "?" + query
-	data, err := f.send(method, url)
+	data, err := f.send(ctx, method, url)
 	if err != nil {
 		return result, fmt.Errorf("could not %+v %+v - %w", method, url, err)
 	}
@@ -54,11 +64,14 @@ func (f *fetcher[T]) FetchWhere(query string) ([]T, error) {
 	return result, err
 }
 
-func (f *fetcher[_]) send(method string, uri string) ([]byte, error) {
-	req, err := http.NewRequest(method, uri, nil)
+func (f *fetcher[_]) send(ctx context.Context, method string, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, uri, nil)
 	if err != nil {
 		return nil, fmt.Errorf("could not construct request %w", err)
 	}
+	if id, ok := requestid.FromContext(ctx); ok {
+		req.Header.Set(requestid.Header, id)
+	}
 	resp, err := f.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get a response %w", err)
@@ -0,0 +1,47 @@
+package json
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"requestid"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestFetchersPropagateRequestIDs(t *testing.T) {
+	var ids []string
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(requestid.Header))
+		paths = append(paths, r.URL.RequestURI())
+		if r.URL.RawQuery != "" {
+			w.Write([]byte(`[{"id":1,"name":"Leanne"}]`))
+			return
+		}
+		w.Write([]byte(`{"id":1,"name":"Leanne"}`))
+	}))
+	defer server.Close()
+	users := NewFetcher[user](server.Client(), server.URL+"/users")
+	ctx := requestid.NewContext(context.Background(), "abc-123")
+
+	found, err := users.FetchByIdContext(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, user{1, "Leanne"}, found)
+
+	all, err := users.FetchWhereContext(ctx, "name=Leanne")
+	assert.Nil(t, err)
+	assert.Equal(t, []user{{1, "Leanne"}}, all)
+
+	_, err = users.FetchById("1")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"abc-123", "abc-123", ""}, ids)
+	assert.Equal(t, []string{"/users/1", "/users?name=Leanne", "/users/1"}, paths)
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"config"
+	"context"
+	"fmt"
 	"http-repository/json"
-	"log"
-	"net/http"
 	"os"
+	"requestid"
+	"tracing"
+	"write-to-stderr/logging"
 )
 
 type User struct {
@@ -23,24 +27,48 @@ type Post struct {
 var userURL = "https://jsonplaceholder.typicode.com/users"
 var postsURL = "https://jsonplaceholder.typicode.com/posts"
 
+type Config struct {
+	Logging logging.Config
+	Tracing tracing.Config
+}
+
 func main() {
-	stdout := log.New(os.Stdout, "info - ", 0)
-	stderr := log.New(os.Stderr, "error - ", 0)
+	var c Config
+
+	if err := config.Load(&c); err != nil {
+		logging.Default.Error("invalid configuration", "err", err)
+		os.Exit(1)
+	}
+
+	id := requestid.New()
+	ctx := requestid.NewContext(context.Background(), id)
+	logger := c.Logging.NewLogger().With("requestId", id)
+
+	tracer, err := c.Tracing.NewTracer("http-repository")
+
+	if err != nil {
+		logger.Error("could not create tracer", "err", err)
+		os.Exit(1)
+	}
+
+	defer tracer.Close()
+
+	client := tracing.NewClient(tracer)
 
-	usersData := json.NewFetcher[User](http.DefaultClient, userURL)
+	usersData := json.NewFetcher[User](client, userURL)
 
-	if user, err := usersData.FetchById("1"); err != nil {
-		stderr.Printf("user error is: %v\n", err)
+	if user, err := usersData.FetchByIdContext(ctx, "1"); err != nil {
+		logger.Error("could not fetch user", "err", err)
 	} else {
-		stdout.Printf("user data is: %#v\n", user)
+		logger.Info("fetched user", "user", fmt.Sprintf("%#v", user))
 	}
 
-	postsData := json.NewFetcher[Post](http.DefaultClient, postsURL)
+	postsData := json.NewFetcher[Post](client, postsURL)
 
-	if posts, err := postsData.FetchWhere("userId=1"); err != nil {
-		stderr.Printf("posts error is %v\n", err)
+	if posts, err := postsData.FetchWhereContext(ctx, "userId=1"); err != nil {
+		logger.Error("could not fetch posts", "err", err)
 	} else {
-		stdout.Printf("posts data is: %#v\n", posts)
+		logger.Info("fetched posts", "posts", fmt.Sprintf("%#v", posts))
 	}
 
 }
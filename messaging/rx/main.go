@@ -1,15 +1,21 @@
 package main
 
 import (
+	"config"
 	"context"
 	"encoding/json"
 	"log"
-	"os"
+	"requestid"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 )
 
+type Config struct {
+	ProjectID      string `env:"GOOGLE_PROJECT_ID" required:"true"`
+	SubscriptionID string `env:"GOOGLE_PUB_SUB_SUBSCRIPTION" required:"true"`
+}
+
 type Message struct {
 	Text string    `json:"text"`
 	Time time.Time `json:"time"`
@@ -17,20 +23,15 @@ type Message struct {
 
 func main() {
 
-	projectID, ok := os.LookupEnv("GOOGLE_PROJECT_ID")
-
-	if !ok {
-		log.Fatal("GOOGLE_PROJECT_ID env var not set")
-	}
-	subscriptionID, ok := os.LookupEnv("GOOGLE_PUB_SUB_SUBSCRIPTION")
+	var c Config
 
-	if !ok {
-		log.Fatal("GOOGLE_PUB_SUB_SUBSCRIPTION env var not set")
+	if err := config.Load(&c); err != nil {
+		log.Fatal(err)
 	}
 
 	ctx := context.Background()
 
-	client, err := pubsub.NewClient(ctx, projectID)
+	client, err := pubsub.NewClient(ctx, c.ProjectID)
 
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
@@ -38,7 +39,7 @@ func main() {
 
 	defer client.Close()
 
-	subscription := client.Subscription(subscriptionID)
+	subscription := client.Subscription(c.SubscriptionID)
 
 	subscription.Receive(ctx, func(_ context.Context, msg *pubsub.Message) {
 		var message Message
@@ -46,7 +47,7 @@ func main() {
 		if err := json.Unmarshal(msg.Data, &message); err != nil {
 			log.Fatalf("could not deserialize message data %v", string(msg.Data))
 		} else {
-			log.Printf("Got message: %v\n", message)
+			log.Printf("Got message: %v, request ID: %v\n", message, msg.Attributes[requestid.Attribute])
 			msg.Ack()
 		}
 	})
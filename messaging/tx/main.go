@@ -1,15 +1,21 @@
 package main
 
 import (
+	"config"
 	"context"
 	"log"
-	"os"
+	"requestid"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/goccy/go-json"
 )
 
+type Config struct {
+	ProjectID string `env:"GOOGLE_PROJECT_ID" required:"true"`
+	TopicID   string `env:"GOOGLE_PUB_SUB_TOPIC" required:"true"`
+}
+
 type Message struct {
 	Text string    `json:"text"`
 	Time time.Time `json:"time"`
@@ -17,20 +23,14 @@ type Message struct {
 
 func main() {
 
-	projectID, ok := os.LookupEnv("GOOGLE_PROJECT_ID")
-
-	if !ok {
-		log.Fatal("GOOGLE_PROJECT_ID env var not set")
-	}
-
-	topicID, ok := os.LookupEnv("GOOGLE_PUB_SUB_TOPIC")
+	var c Config
 
-	if !ok {
-		log.Fatal("GOOGLE_PUB_SUB_TOPIC env var not set")
+	if err := config.Load(&c); err != nil {
+		log.Fatal(err)
 	}
 
 	ctx := context.Background()
-	client, err := pubsub.NewClient(ctx, projectID)
+	client, err := pubsub.NewClient(ctx, c.ProjectID)
 
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
@@ -38,7 +38,7 @@ func main() {
 
 	defer client.Close()
 
-	topic := client.Topic(topicID)
+	topic := client.Topic(c.TopicID)
 
 	for {
 		time.Sleep(3 * time.Second)
@@ -50,13 +50,15 @@ func main() {
 		if err != nil {
 			log.Fatalf("pubsub: result.Get: %v", err)
 		}
+		requestID := requestid.New()
 		result := topic.Publish(ctx, &pubsub.Message{
-			Data: data,
+			Data:       data,
+			Attributes: map[string]string{requestid.Attribute: requestID},
 		})
 		if id, err := result.Get(ctx); err != nil {
 			log.Fatalf("pubsub: result.Get: %v", err)
 		} else {
-			log.Printf("Published a message; msg ID: %v\n", id)
+			log.Printf("Published a message; msg ID: %v, request ID: %v\n", id, requestID)
 		}
 	}
 }
@@ -0,0 +1,67 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var ErrUsage = errors.New("usage: migrate up | down [N|all] | status")
+
+// Run carries out a migrate subcommand, printing what it did to w.
+func (m *Migrator) Run(ctx context.Context, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	switch args[0] {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, migration := range done {
+			fmt.Fprintf(w, "applied %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "down":
+		steps, err := parseSteps(args[1:])
+		if err != nil {
+			return err
+		}
+		done, err := m.Down(ctx, steps)
+		for _, migration := range done {
+			fmt.Fprintf(w, "reverted %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05Z07:00")
+			}
+			fmt.Fprintf(w, "%d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return ErrUsage
+	}
+}
+
+func parseSteps(args []string) (int, error) {
+	switch {
+	case len(args) == 0:
+		return 1, nil
+	case len(args) > 1:
+		return 0, ErrUsage
+	case args[0] == "all":
+		return int(^uint(0) >> 1), nil
+	}
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 {
+		return 0, ErrUsage
+	}
+	return steps, nil
+}
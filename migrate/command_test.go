@@ -0,0 +1,32 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunUsage(t *testing.T) {
+	m, err := New(nil, fstest.MapFS{})
+	assert.Nil(t, err)
+	var out bytes.Buffer
+	for _, args := range [][]string{{}, {"sideways"}, {"down", "0"}, {"down", "1", "2"}} {
+		assert.ErrorIs(t, m.Run(context.Background(), args, &out), ErrUsage, args)
+	}
+	assert.Empty(t, out.String())
+}
+
+func TestParseSteps(t *testing.T) {
+	steps, err := parseSteps(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, steps)
+	steps, err = parseSteps([]string{"3"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, steps)
+	steps, err = parseSteps([]string{"all"})
+	assert.Nil(t, err)
+	assert.Greater(t, steps, 1000)
+}
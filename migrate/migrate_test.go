@@ -0,0 +1,91 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSortsAndPairsScripts(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"2_add-column.up.sql":     {Data: []byte("up 2")},
+		"2_add-column.down.sql":   {Data: []byte("down 2")},
+		"1_create-table.up.sql":   {Data: []byte("up 1")},
+		"1_create-table.down.sql": {Data: []byte("down 1")},
+		"README.md":               {Data: []byte("ignored")},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "create-table", Up: "up 1", Down: "down 1"},
+		{Version: 2, Name: "add-column", Up: "up 2", Down: "down 2"},
+	}, migrations)
+}
+
+func TestLoadRejectsMissingUpScript(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"1_create-table.down.sql": {Data: []byte("down 1")},
+	})
+	assert.ErrorContains(t, err, "has no up script")
+}
+
+func TestLoadRejectsConflictingNames(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"1_create-table.up.sql":    {Data: []byte("up 1")},
+		"1_create-tables.down.sql": {Data: []byte("down 1")},
+	})
+	assert.ErrorContains(t, err, "conflicting names")
+}
+
+func TestUpStatusAndDown(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	assert.Nil(t, err)
+	defer db.Close()
+	ctx := context.Background()
+	m, err := New(db, fstest.MapFS{
+		"1_create-table-migrate-test.up.sql":   {Data: []byte(`create table "migrateTest" ("id" int)`)},
+		"1_create-table-migrate-test.down.sql": {Data: []byte(`drop table "migrateTest"`)},
+		"2_add-column-migrate-test.up.sql":     {Data: []byte(`alter table "migrateTest" add column "name" text`)},
+		"2_add-column-migrate-test.down.sql":   {Data: []byte(`alter table "migrateTest" drop column "name"`)},
+	})
+	assert.Nil(t, err)
+	defer m.Down(ctx, 2)
+
+	done, err := m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, done, 2)
+	done, err = m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, done, 0)
+
+	statuses, err := m.Status(ctx)
+	assert.Nil(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.NotNil(t, statuses[1].AppliedAt)
+
+	done, err = m.Down(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), done[0].Version)
+	statuses, err = m.Status(ctx)
+	assert.Nil(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+}
+
+func TestWithTableSeparatesHistoryAndLock(t *testing.T) {
+	fsys := fstest.MapFS{"1_noop.up.sql": {Data: []byte(`select 1`)}}
+	a, err := New(nil, fsys)
+	assert.Nil(t, err)
+	b, err := New(nil, fsys, WithTable("otherMigrations"))
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultTable, a.table)
+	assert.Equal(t, "otherMigrations", b.table)
+	assert.NotEqual(t, a.lockID, b.lockID)
+}
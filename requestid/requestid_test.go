@@ -0,0 +1,33 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	id := New()
+	assert.Len(t, id, 32)
+	assert.True(t, Valid(id))
+	assert.NotEqual(t, id, New())
+}
+
+func TestResolve(t *testing.T) {
+	assert.Equal(t, "abc-123", Resolve("abc-123"))
+	for _, invalid := range []string{"", "has space", "new\nline", strings.Repeat("a", MaxLength+1)} {
+		resolved := Resolve(invalid)
+		assert.NotEqual(t, invalid, resolved)
+		assert.Len(t, resolved, 32)
+	}
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+	id, ok := FromContext(NewContext(context.Background(), "abc-123"))
+	assert.True(t, ok)
+	assert.Equal(t, "abc-123", id)
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashAndCheckPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	assert.Nil(t, err)
+	assert.True(t, CheckPassword(hash, "correct horse"))
+	assert.False(t, CheckPassword(hash, "wrong horse"))
+	assert.False(t, CheckPassword("", "correct horse"))
+}
+
+func TestIssueAndVerifyToken(t *testing.T) {
+	tokens := NewTokens([]byte("secret"), time.Hour)
+	token, expiresAt, err := tokens.Issue("user-1")
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Second)
+	assert.Equal(t, 3, len(strings.Split(token, ".")))
+	subject, err := tokens.Verify(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-1", subject)
+}
+
+func TestVerifyRejectsForeignAndTamperedTokens(t *testing.T) {
+	tokens := NewTokens([]byte("secret"), time.Hour)
+	token, _, _ := tokens.Issue("user-1")
+	forged, _, _ := NewTokens([]byte("guess"), time.Hour).Issue("user-1")
+	for _, bad := range []string{"", "nonsense", forged, token + "x", strings.Replace(token, ".", "..", 1)} {
+		_, err := tokens.Verify(bad)
+		assert.ErrorIs(t, err, ErrInvalidToken, bad)
+	}
+}
+
+func TestVerifyRejectsExpiredTokens(t *testing.T) {
+	tokens := NewTokens([]byte("secret"), time.Hour)
+	token, _, _ := tokens.Issue("user-1")
+	tokens.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err := tokens.Verify(token)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type Tokens struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewTokens(secret []byte, ttl time.Duration) *Tokens {
+	return &Tokens{secret, ttl, time.Now}
+}
+
+func (t *Tokens) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (t *Tokens) Issue(subject string) (string, time.Time, error) {
+	issuedAt := t.now()
+	expiresAt := issuedAt.Add(t.ttl)
+	body, err := json.Marshal(claims{subject, issuedAt.Unix(), expiresAt.Unix()})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	payload := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(body)
+	return payload + "." + t.sign(payload), time.Unix(expiresAt.Unix(), 0).UTC(), nil
+}
+
+func (t *Tokens) Verify(token string) (string, error) {
+	dot := strings.LastIndexByte(token, '.')
+	if dot < 0 {
+		return "", ErrInvalidToken
+	}
+	payload, signature := token[:dot], token[dot+1:]
+	if !hmac.Equal([]byte(signature), []byte(t.sign(payload))) {
+		return "", ErrInvalidToken
+	}
+	header, body, ok := strings.Cut(payload, ".")
+	if !ok || header != tokenHeader {
+		return "", ErrInvalidToken
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	var c claims
+	if err := json.Unmarshal(decoded, &c); err != nil || c.Subject == "" {
+		return "", ErrInvalidToken
+	}
+	if t.now().Unix() >= c.ExpiresAt {
+		return "", ErrTokenExpired
+	}
+	return c.Subject, nil
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"time"
+	"todo-app/validation"
+)
+
+const MaxAPIKeyNameLength = 100
+
+type Scope string
+
+const (
+	ScopeTodosRead  Scope = "todos:read"
+	ScopeTodosWrite Scope = "todos:write"
+	ScopeAPIKeys    Scope = "apikeys"
+)
+
+var (
+	APIKeyScopes  = []Scope{ScopeTodosRead, ScopeTodosWrite}
+	SessionScopes = []Scope{ScopeTodosRead, ScopeTodosWrite, ScopeAPIKeys}
+)
+
+func HasScope(scopes []Scope, scope Scope) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKey struct {
+	ID        string    `json:"apiKeyId" validate:"readonly"`
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix" validate:"readonly"`
+	Scopes    []Scope   `json:"scopes"`
+	UserID    string    `json:"-"`
+	KeyHash   string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt" validate:"readonly"`
+}
+
+func (k APIKey) Validate() validation.Errors {
+	errs := validation.Collect(
+		validation.Required("name", k.Name),
+		validation.MaxLength("name", k.Name, MaxAPIKeyNameLength),
+	)
+	if len(k.Scopes) == 0 {
+		errs = append(errs, validation.FieldError{Field: "scopes", Detail: "must not be empty"})
+	}
+	for _, scope := range k.Scopes {
+		if !HasScope(APIKeyScopes, scope) {
+			errs = append(errs, validation.FieldError{
+				Field:  "scopes",
+				Detail: "must contain only todos:read or todos:write",
+			})
+			break
+		}
+	}
+	return errs
+}
@@ -0,0 +1,71 @@
+package data
+
+import (
+	"fmt"
+	"todo-app/validation"
+
+	"github.com/google/uuid"
+)
+
+const MaxBatchSize = 100
+
+type TodoOp string
+
+const (
+	OpCreate TodoOp = "create"
+	OpUpdate TodoOp = "update"
+	OpDelete TodoOp = "delete"
+)
+
+type TodoOperation struct {
+	Op   TodoOp `json:"op"`
+	ID   string `json:"todoId,omitempty"`
+	Todo *Todo  `json:"todo,omitempty"`
+}
+
+func (o TodoOperation) Validate() validation.Errors {
+	var errs validation.Errors
+	switch o.Op {
+	case OpCreate:
+		if o.ID != "" {
+			errs = append(errs, validation.FieldError{Field: "todoId", Detail: "must not be set when creating"})
+		}
+	case OpUpdate, OpDelete:
+		if _, err := uuid.Parse(o.ID); err != nil {
+			errs = append(errs, validation.FieldError{Field: "todoId", Detail: "must be a UUID"})
+		}
+	default:
+		errs = append(errs, validation.FieldError{Field: "op", Detail: "must be one of create, update or delete"})
+	}
+	switch {
+	case o.Op == OpDelete:
+	case o.Todo == nil:
+		errs = append(errs, validation.FieldError{Field: "todo", Detail: "is required"})
+	default:
+		errs = append(errs, validation.Nest("todo", o.Todo.Validate())...)
+	}
+	return errs
+}
+
+type TodoBatch struct {
+	Operations []TodoOperation `json:"operations"`
+}
+
+func (b TodoBatch) Validate() validation.Errors {
+	if len(b.Operations) == 0 || len(b.Operations) > MaxBatchSize {
+		return validation.Errors{{
+			Field:  "operations",
+			Detail: fmt.Sprintf("must contain from 1 to %d operations", MaxBatchSize),
+		}}
+	}
+	var errs validation.Errors
+	for i, op := range b.Operations {
+		errs = append(errs, validation.Nest(fmt.Sprintf("operations[%d]", i), op.Validate())...)
+	}
+	return errs
+}
+
+type TodoOperationResult struct {
+	Todo *Todo
+	Err  error
+}
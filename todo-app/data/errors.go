@@ -0,0 +1,20 @@
+package data
+
+import "errors"
+
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrInvalidCursor      = errors.New("invalid cursor")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrBatchAborted       = errors.New("batch aborted")
+	ErrUnauthenticated    = errors.New("unauthenticated")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrListNotFound       = errors.New("list does not exist")
+	ErrParentNotFound     = errors.New("parent todo does not exist")
+	ErrSiblingNotFound    = errors.New("sibling todo does not exist")
+	ErrCyclicParent       = errors.New("todo cannot be moved under itself")
+	ErrEventNotFound      = errors.New("event is not in the todo's history")
+	ErrRevertCrossesMove  = errors.New("todo has been moved since the event")
+	ErrParentChanged      = errors.New("parent todo can only be changed by moving the todo")
+)
@@ -0,0 +1,127 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+	"todo-app/validation"
+)
+
+type TodoEventKind string
+
+const (
+	EventCreated  TodoEventKind = "created"
+	EventUpdated  TodoEventKind = "updated"
+	EventDeleted  TodoEventKind = "deleted"
+	EventRestored TodoEventKind = "restored"
+	EventMoved    TodoEventKind = "moved"
+	EventReverted TodoEventKind = "reverted"
+)
+
+// TodoChanges holds todo fields by their JSON name, as the API shows them.
+type TodoChanges map[string]json.RawMessage
+
+func (c *TodoChanges) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, c)
+	default:
+		return fmt.Errorf("cannot scan %v into todo changes", src)
+	}
+}
+
+type TodoEvent struct {
+	ID        int64         `json:"eventId"`
+	TodoID    string        `json:"todoId"`
+	ActorID   string        `json:"actorId"`
+	Kind      TodoEventKind `json:"kind"`
+	Before    TodoChanges   `json:"before"`
+	After     TodoChanges   `json:"after"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+type TodoHistory struct {
+	Events []TodoEvent `json:"events"`
+}
+
+type TodoRevert struct {
+	EventID int64 `json:"eventId"`
+}
+
+func (r TodoRevert) Validate() validation.Errors {
+	if r.EventID < 1 {
+		return validation.Errors{{Field: "eventId", Detail: "must be a positive integer"}}
+	}
+	return nil
+}
+
+func changesOf(todo Todo) (TodoChanges, error) {
+	b, err := json.Marshal(todo)
+	if err != nil {
+		return nil, err
+	}
+	var changes TodoChanges
+	if err := json.Unmarshal(b, &changes); err != nil {
+		return nil, err
+	}
+	delete(changes, "todoId")
+	delete(changes, "createdAt")
+	delete(changes, "updatedAt")
+	return changes, nil
+}
+
+// DiffTodos returns the fields that differ between two versions of a todo,
+// with their values before and after. A todo with no previous version has
+// every field in after.
+func DiffTodos(before *Todo, after Todo) (TodoChanges, TodoChanges, error) {
+	next, err := changesOf(after)
+	if err != nil || before == nil {
+		return nil, next, err
+	}
+	previous, err := changesOf(*before)
+	if err != nil {
+		return nil, nil, err
+	}
+	removed, added := TodoChanges{}, TodoChanges{}
+	for field, value := range next {
+		if !bytes.Equal(previous[field], value) {
+			removed[field], added[field] = previous[field], value
+		}
+	}
+	return removed, added, nil
+}
+
+// RevertTodo rebuilds a todo as it was before the given events by undoing
+// them from the newest back. Moves renumber siblings, so they cannot be
+// undone field by field and are refused.
+func RevertTodo(current Todo, events []TodoEvent) (Todo, error) {
+	for _, event := range events {
+		if event.Kind == EventMoved {
+			return Todo{}, ErrRevertCrossesMove
+		}
+	}
+	state, err := changesOf(current)
+	if err != nil {
+		return Todo{}, err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID > events[j].ID })
+	for _, event := range events {
+		for field, value := range event.Before {
+			state[field] = value
+		}
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return Todo{}, err
+	}
+	reverted := current
+	if err := json.Unmarshal(b, &reverted); err != nil {
+		return Todo{}, err
+	}
+	return reverted, nil
+}
@@ -0,0 +1,27 @@
+package data
+
+import (
+	"time"
+	"todo-app/validation"
+)
+
+const MaxListNameLength = 100
+
+type List struct {
+	ID        string    `json:"listId" validate:"readonly"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt" validate:"readonly"`
+	UpdatedAt time.Time `json:"updatedAt" validate:"readonly"`
+}
+
+func (l List) Validate() validation.Errors {
+	return validation.Collect(
+		validation.Required("name", l.Name),
+		validation.MaxLength("name", l.Name, MaxListNameLength),
+	)
+}
+
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
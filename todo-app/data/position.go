@@ -0,0 +1,74 @@
+package data
+
+import (
+	"todo-app/validation"
+)
+
+const PositionGap = 1024
+
+type TodoMove struct {
+	ParentID Nullable[string] `json:"parentTodoId"`
+	After    *string          `json:"afterTodoId"`
+	Before   *string          `json:"beforeTodoId"`
+}
+
+func (m TodoMove) Validate() validation.Errors {
+	errs := validation.Collect(
+		validID("parentTodoId", m.ParentID.Value),
+		validID("afterTodoId", m.After),
+		validID("beforeTodoId", m.Before),
+	)
+	if m.After != nil && m.Before != nil {
+		errs = append(errs, validation.FieldError{Field: "beforeTodoId", Detail: "must not be set with afterTodoId"})
+	}
+	return errs
+}
+
+type Sibling struct {
+	ID       string
+	Position float64
+}
+
+func positionBetween(siblings []Sibling, i int) (float64, bool) {
+	switch {
+	case len(siblings) == 0:
+		return PositionGap, true
+	case i == 0:
+		return siblings[0].Position - PositionGap, true
+	case i == len(siblings):
+		return siblings[i-1].Position + PositionGap, true
+	}
+	lower, upper := siblings[i-1].Position, siblings[i].Position
+	position := lower + (upper-lower)/2
+	return position, position > lower && position < upper
+}
+
+// PlaceTodo finds the position for a todo moved among its new siblings, which
+// must be in position order. Only when two neighbours are too close to split
+// are the siblings renumbered, and those are returned for saving.
+func PlaceTodo(siblings []Sibling, m TodoMove) (float64, []Sibling, error) {
+	i := len(siblings)
+	if m.After != nil || m.Before != nil {
+		i = -1
+		for n, sibling := range siblings {
+			switch {
+			case m.After != nil && sibling.ID == *m.After:
+				i = n + 1
+			case m.Before != nil && sibling.ID == *m.Before:
+				i = n
+			}
+		}
+		if i < 0 {
+			return 0, nil, ErrSiblingNotFound
+		}
+	}
+	if position, ok := positionBetween(siblings, i); ok {
+		return position, nil, nil
+	}
+	renumbered := make([]Sibling, len(siblings))
+	for n, sibling := range siblings {
+		renumbered[n] = Sibling{sibling.ID, float64(n+1) * PositionGap}
+	}
+	position, _ := positionBetween(renumbered, i)
+	return position, renumbered, nil
+}
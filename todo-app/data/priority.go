@@ -0,0 +1,48 @@
+package data
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+var priorities = []Priority{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+func (p Priority) Rank() int {
+	for rank, priority := range priorities {
+		if p == priority {
+			return rank
+		}
+	}
+	return PriorityNormal.Rank()
+}
+
+func (p Priority) Valid() bool {
+	for _, priority := range priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Priority) Value() (driver.Value, error) {
+	return int64(p.Rank()), nil
+}
+
+func (p *Priority) Scan(src any) error {
+	rank, ok := src.(int64)
+	if !ok || rank < 0 || int(rank) >= len(priorities) {
+		return fmt.Errorf("cannot scan %v into a priority", src)
+	}
+	*p = priorities[rank]
+	return nil
+}
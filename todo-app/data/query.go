@@ -0,0 +1,114 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type TodoSort string
+
+const (
+	SortCreatedAt     TodoSort = "createdAt"
+	SortCreatedAtDesc TodoSort = "-createdAt"
+	SortUpdatedAt     TodoSort = "updatedAt"
+	SortUpdatedAtDesc TodoSort = "-updatedAt"
+	SortPriority      TodoSort = "priority"
+	SortPriorityDesc  TodoSort = "-priority"
+	SortPosition      TodoSort = "position"
+	SortPositionDesc  TodoSort = "-position"
+)
+
+func ParseTodoSort(s string) (TodoSort, error) {
+	switch sort := TodoSort(s); sort {
+	case "":
+		return SortCreatedAt, nil
+	case SortCreatedAt, SortCreatedAtDesc, SortUpdatedAt, SortUpdatedAtDesc, SortPriority, SortPriorityDesc,
+		SortPosition, SortPositionDesc:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("unsupported sort %q", s)
+	}
+}
+
+type TodoCursor struct {
+	Sort     TodoSort  `json:"s"`
+	Time     time.Time `json:"t"`
+	Rank     int       `json:"r,omitempty"`
+	Position float64   `json:"p,omitempty"`
+	ID       string    `json:"i"`
+}
+
+func NewTodoCursor(sort TodoSort, todo Todo) TodoCursor {
+	cursor := TodoCursor{Sort: sort, ID: todo.ID}
+	switch sort {
+	case SortUpdatedAt, SortUpdatedAtDesc:
+		cursor.Time = todo.UpdatedAt
+	case SortPriority, SortPriorityDesc:
+		cursor.Rank = todo.Priority.Rank()
+	case SortPosition, SortPositionDesc:
+		cursor.Position = todo.Position
+	default:
+		cursor.Time = todo.CreatedAt
+	}
+	return cursor
+}
+
+func (c TodoCursor) Key() any {
+	switch c.Sort {
+	case SortPriority, SortPriorityDesc:
+		return c.Rank
+	case SortPosition, SortPositionDesc:
+		return c.Position
+	default:
+		return c.Time
+	}
+}
+
+func (c TodoCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeTodoCursor(s string) (*TodoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor TodoCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := ParseTodoSort(string(cursor.Sort)); err != nil || cursor.Sort == "" {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := uuid.Parse(cursor.ID); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+type TodoQuery struct {
+	Limit       int
+	Cursor      *TodoCursor
+	IsCompleted *bool
+	Overdue     *bool
+	ListID      *uuid.UUID
+	ParentID    *uuid.UUID
+	Tag         string
+	Search      string
+	Sort        TodoSort
+}
+
+type TodoPage struct {
+	Todos      []Todo  `json:"todos"`
+	NextCursor *string `json:"nextCursor"`
+}
@@ -0,0 +1,16 @@
+package data
+
+type TodoSearch struct {
+	Query string
+	Limit int
+}
+
+type TodoSearchResult struct {
+	Todo    Todo    `json:"todo"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type TodoSearchPage struct {
+	Results []TodoSearchResult `json:"results"`
+}
@@ -1,13 +1,147 @@
 package data
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
+	"todo-app/validation"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxTaskLength = 500
+	MaxTagLength  = 50
+	MaxTags       = 20
 )
 
 type Todo struct {
-	ID          string    `json:"todoId"`
-	Task        string    `json:"task"`
-	IsCompleted bool      `json:"isCompleted"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID               string     `json:"todoId" validate:"readonly"`
+	Task             string     `json:"task"`
+	IsCompleted      bool       `json:"isCompleted"`
+	DueAt            *time.Time `json:"dueAt"`
+	Priority         Priority   `json:"priority"`
+	CompletedAt      *time.Time `json:"completedAt" validate:"readonly"`
+	ListID           *string    `json:"listId"`
+	Tags             []string   `json:"tags"`
+	ParentID         *string    `json:"parentTodoId"`
+	Position         float64    `json:"position" validate:"readonly"`
+	DeriveCompletion bool       `json:"deriveCompletion"`
+	CreatedAt        time.Time  `json:"createdAt" validate:"readonly"`
+	UpdatedAt        time.Time  `json:"updatedAt" validate:"readonly"`
+}
+
+func (t Todo) Validate() validation.Errors {
+	var priority *validation.FieldError
+	if t.Priority != "" {
+		priority = validPriority(t.Priority)
+	}
+	errs := validation.Collect(
+		validation.Required("task", t.Task),
+		validation.MaxLength("task", t.Task, MaxTaskLength),
+		priority,
+		validID("listId", t.ListID),
+		validID("parentTodoId", t.ParentID),
+	)
+	return append(errs, validTags(t.Tags)...)
+}
+
+func (t Todo) IsOverdue(now time.Time) bool {
+	return !t.IsCompleted && t.DueAt != nil && t.DueAt.Before(now)
+}
+
+func validPriority(p Priority) *validation.FieldError {
+	if !p.Valid() {
+		return &validation.FieldError{Field: "priority", Detail: "must be one of low, normal, high or urgent"}
+	}
+	return nil
+}
+
+func validID(field string, id *string) *validation.FieldError {
+	if id == nil {
+		return nil
+	}
+	if _, err := uuid.Parse(*id); err != nil {
+		return &validation.FieldError{Field: field, Detail: "must be a UUID"}
+	}
+	return nil
+}
+
+func validTags(tags []string) validation.Errors {
+	if len(tags) > MaxTags {
+		return validation.Errors{{Field: "tags", Detail: fmt.Sprintf("must contain at most %d tags", MaxTags)}}
+	}
+	var errs validation.Errors
+	for i, tag := range tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		errs = append(errs, validation.Collect(
+			validation.Required(field, tag),
+			validation.MaxLength(field, tag, MaxTagLength),
+		)...)
+	}
+	return errs
+}
+
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if bytes.Equal(data, []byte("null")) {
+		n.Value = nil
+		return nil
+	}
+	return json.Unmarshal(data, &n.Value)
+}
+
+type TodoPatch struct {
+	Task             *string             `json:"task"`
+	IsCompleted      *bool               `json:"isCompleted"`
+	DueAt            Nullable[time.Time] `json:"dueAt"`
+	Priority         *Priority           `json:"priority"`
+	ListID           Nullable[string]    `json:"listId"`
+	Tags             *[]string           `json:"tags"`
+	DeriveCompletion *bool               `json:"deriveCompletion"`
+}
+
+func (p TodoPatch) Validate() validation.Errors {
+	var errs []*validation.FieldError
+	if p.Task != nil {
+		errs = append(errs,
+			validation.Required("task", *p.Task),
+			validation.MaxLength("task", *p.Task, MaxTaskLength),
+		)
+	}
+	if p.Priority != nil {
+		errs = append(errs, validPriority(*p.Priority))
+	}
+	errs = append(errs, validID("listId", p.ListID.Value))
+	all := validation.Collect(errs...)
+	if p.Tags != nil {
+		all = append(all, validTags(*p.Tags)...)
+	}
+	return all
 }
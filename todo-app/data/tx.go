@@ -0,0 +1,67 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultTxAttempts            = 3
+	sqlStateSerializationFailure = "40001"
+)
+
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+type TxRunner struct {
+	DB          TxBeginner
+	Options     *sql.TxOptions
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+func NewTxRunner(db TxBeginner) *TxRunner {
+	return &TxRunner{DB: db, MaxAttempts: DefaultTxAttempts, Backoff: 10 * time.Millisecond}
+}
+
+func IsSerializationFailure(err error) bool {
+	return SQLState(err) == sqlStateSerializationFailure
+}
+
+func (r *TxRunner) Run(ctx context.Context, fn func(tx DB) error) error {
+	for attempt := 1; ; attempt++ {
+		err := r.run(ctx, fn)
+		if attempt >= r.MaxAttempts || !IsSerializationFailure(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * r.Backoff):
+		}
+	}
+}
+
+func (r *TxRunner) run(ctx context.Context, fn func(tx DB) error) error {
+	tx, err := r.DB.BeginTx(ctx, r.Options)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDB struct {
+	begins, commits, rollbacks int
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) { return d, nil }
+func (d *fakeDB) Driver() driver.Driver                        { return nil }
+func (d *fakeDB) Prepare(string) (driver.Stmt, error)          { return nil, errors.New("not supported") }
+func (d *fakeDB) Close() error                                 { return nil }
+func (d *fakeDB) Begin() (driver.Tx, error)                    { d.begins++; return d, nil }
+func (d *fakeDB) Commit() error                                { d.commits++; return nil }
+func (d *fakeDB) Rollback() error                              { d.rollbacks++; return nil }
+
+func newTxRunner() (*TxRunner, *fakeDB) {
+	fake := &fakeDB{}
+	r := NewTxRunner(sql.OpenDB(fake))
+	r.Backoff = 0
+	return r, fake
+}
+
+func TestTxRunnerCommits(t *testing.T) {
+	r, fake := newTxRunner()
+	err := r.Run(context.Background(), func(tx DB) error {
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, fake.commits)
+	assert.Equal(t, 0, fake.rollbacks)
+}
+
+func TestTxRunnerRollsBackOnError(t *testing.T) {
+	r, fake := newTxRunner()
+	oops := errors.New("oops!")
+	err := r.Run(context.Background(), func(tx DB) error {
+		return oops
+	})
+	assert.ErrorIs(t, err, oops)
+	assert.Equal(t, 1, fake.begins)
+	assert.Equal(t, 0, fake.commits)
+	assert.Equal(t, 1, fake.rollbacks)
+}
+
+func TestTxRunnerRollsBackOnPanic(t *testing.T) {
+	r, fake := newTxRunner()
+	assert.PanicsWithValue(t, "oops!", func() {
+		r.Run(context.Background(), func(tx DB) error {
+			panic("oops!")
+		})
+	})
+	assert.Equal(t, 0, fake.commits)
+	assert.Equal(t, 1, fake.rollbacks)
+}
+
+func TestTxRunnerRetriesSerializationFailures(t *testing.T) {
+	r, fake := newTxRunner()
+	attempts := 0
+	err := r.Run(context.Background(), func(tx DB) error {
+		attempts++
+		if attempts < 2 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 1, fake.commits)
+	assert.Equal(t, 1, fake.rollbacks)
+}
+
+func TestTxRunnerGivesUpAfterMaxAttempts(t *testing.T) {
+	r, fake := newTxRunner()
+	attempts := 0
+	err := r.Run(context.Background(), func(tx DB) error {
+		attempts++
+		return &pq.Error{Code: "40001"}
+	})
+	assert.True(t, IsSerializationFailure(err))
+	assert.Equal(t, DefaultTxAttempts, attempts)
+	assert.Equal(t, DefaultTxAttempts, fake.rollbacks)
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+	assert.True(t, IsSerializationFailure(&pq.Error{Code: "40001"}))
+	assert.False(t, IsSerializationFailure(&pq.Error{Code: "23505"}))
+	assert.False(t, IsSerializationFailure(errors.New("40001")))
+}
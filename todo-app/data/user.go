@@ -0,0 +1,59 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"time"
+	"todo-app/validation"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxEmailLength    = 254
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+type User struct {
+	ID           string    `json:"userId"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (c Credentials) Validate() validation.Errors {
+	errs := validation.Collect(
+		validation.Required("email", c.Email),
+		validation.MaxLength("email", c.Email, MaxEmailLength),
+	)
+	if len(errs) == 0 {
+		if addr, err := mail.ParseAddress(c.Email); err != nil || addr.Address != c.Email {
+			errs = append(errs, validation.FieldError{Field: "email", Detail: "must be an email address"})
+		}
+	}
+	if len(c.Password) < MinPasswordLength || len(c.Password) > MaxPasswordLength {
+		errs = append(errs, validation.FieldError{
+			Field:  "password",
+			Detail: fmt.Sprintf("must be from %d to %d bytes", MinPasswordLength, MaxPasswordLength),
+		})
+	}
+	return errs
+}
+
+type userIDKey struct{}
+
+func ContextWithUserID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(userIDKey{}).(uuid.UUID)
+	return id, ok
+}
@@ -5,6 +5,8 @@ go 1.20
 require (
 	github.com/google/uuid v1.3.0
 	github.com/lib/pq v1.10.9
+	github.com/prometheus/client_golang v1.15.1
+	golang.org/x/crypto v0.7.0
 )
 
 require (
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"todo-app/auth"
+	. "todo-app/data"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type createAPIKey interface {
+	CreateAPIKey(ctx context.Context, k APIKey) (*APIKey, error)
+}
+
+func CreateAPIKey(k createAPIKey) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var body APIKey
+		if err := bindJSON(c, &body); err != nil {
+			abort(c, err)
+			return
+		}
+		key, prefix, err := auth.GenerateAPIKey()
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		body.Prefix, body.KeyHash = prefix, auth.HashAPIKey(key)
+		created, err := k.CreateAPIKey(c.Request.Context(), body)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, CreatedAPIKey{*created, key})
+	}
+}
+
+type getAPIKeys interface {
+	GetAPIKeys(ctx context.Context) ([]APIKey, error)
+}
+
+func GetAPIKeys(k getAPIKeys) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		keys, err := k.GetAPIKeys(c.Request.Context())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"apiKeys": keys})
+	}
+}
+
+type deleteAPIKeyByID interface {
+	DeleteAPIKeyByID(ctx context.Context, id uuid.UUID) (*APIKey, error)
+}
+
+func DeleteAPIKeyByID(k deleteAPIKeyByID) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		id, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		deleted, err := k.DeleteAPIKeyByID(c.Request.Context(), id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if deleted == nil {
+			abort(c, notFound(fmt.Sprintf("api key %s does not exist", id)))
+			return
+		}
+		c.Status(http.StatusNoContent)
+		c.Writer.WriteHeaderNow()
+	}
+}
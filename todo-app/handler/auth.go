@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+	"todo-app/auth"
+	. "todo-app/data"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type tokenIssuer interface {
+	Issue(subject string) (string, time.Time, error)
+}
+
+type tokenVerifier interface {
+	Verify(token string) (string, error)
+}
+
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      *User     `json:"user"`
+}
+
+func writeSession(c *gin.Context, status int, user *User, tokens tokenIssuer) {
+	token, expiresAt, err := tokens.Issue(user.ID)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	c.JSON(status, Session{token, expiresAt, user})
+}
+
+type createUser interface {
+	CreateUser(ctx context.Context, u User) (*User, error)
+}
+
+func Signup(u createUser, tokens tokenIssuer) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var creds Credentials
+		if err := bindJSON(c, &creds); err != nil {
+			abort(c, err)
+			return
+		}
+		hash, err := auth.HashPassword(creds.Password)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		user, err := u.CreateUser(c.Request.Context(), User{Email: creds.Email, PasswordHash: hash})
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		writeSession(c, http.StatusCreated, user, tokens)
+	}
+}
+
+type getUserByEmail interface {
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+}
+
+func Login(u getUserByEmail, tokens tokenIssuer) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var creds Credentials
+		if err := bindJSON(c, &creds); err != nil {
+			abort(c, err)
+			return
+		}
+		user, err := u.GetUserByEmail(c.Request.Context(), creds.Email)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		var hash string
+		if user != nil {
+			hash = user.PasswordHash
+		}
+		if !auth.CheckPassword(hash, creds.Password) {
+			abort(c, ErrInvalidCredentials)
+			return
+		}
+		writeSession(c, http.StatusOK, user, tokens)
+	}
+}
+
+const (
+	scopesKey   = "todo-app/scopes"
+	apiKeyIDKey = "todo-app/apiKeyId"
+)
+
+type getAPIKeyByHash interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+}
+
+func unauthenticated(c *gin.Context, challenge string) {
+	c.Header("WWW-Authenticate", challenge)
+	abort(c, ErrUnauthenticated)
+}
+
+func Authenticate(tokens tokenVerifier, keys getAPIKeyByHash) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var subject string
+		var scopes []Scope
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			apiKey, err := keys.GetAPIKeyByHash(c.Request.Context(), auth.HashAPIKey(key))
+			if err != nil {
+				abort(c, err)
+				return
+			}
+			if apiKey == nil {
+				unauthenticated(c, `Bearer realm="todo-app"`)
+				return
+			}
+			subject, scopes = apiKey.UserID, apiKey.Scopes
+			c.Set(apiKeyIDKey, apiKey.ID)
+		} else {
+			scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") || token == "" {
+				unauthenticated(c, `Bearer realm="todo-app"`)
+				return
+			}
+			var err error
+			if subject, err = tokens.Verify(token); err != nil {
+				unauthenticated(c, `Bearer realm="todo-app", error="invalid_token"`)
+				return
+			}
+			scopes = SessionScopes
+		}
+		userID, err := uuid.Parse(subject)
+		if err != nil {
+			unauthenticated(c, `Bearer realm="todo-app", error="invalid_token"`)
+			return
+		}
+		c.Set(scopesKey, scopes)
+		c.Request = c.Request.WithContext(ContextWithUserID(c.Request.Context(), userID))
+		c.Next()
+	}
+}
+
+func RequireScope(scope Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, _ := c.Value(scopesKey).([]Scope)
+		if !HasScope(scopes, scope) {
+			abort(c, NewProblem(http.StatusForbidden, "insufficient-scope", "the credentials lack the "+string(scope)+" scope"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo-app/auth"
+	. "todo-app/data"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubTokens struct{}
+
+func (stubTokens) Issue(subject string) (string, time.Time, error) {
+	return "token-for-" + subject, time.Time{}, nil
+}
+
+func (stubTokens) Verify(token string) (string, error) {
+	subject, ok := bytes.CutPrefix([]byte(token), []byte("token-for-"))
+	if !ok {
+		return "", errors.New("bad token")
+	}
+	return string(subject), nil
+}
+
+type stubCreateUser struct {
+	stub func(u User) (*User, error)
+}
+
+func (r stubCreateUser) CreateUser(ctx context.Context, u User) (*User, error) {
+	return r.stub(u)
+}
+
+type stubGetUserByEmail struct {
+	stub func(email string) (*User, error)
+}
+
+func (r stubGetUserByEmail) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return r.stub(email)
+}
+
+func TestSignupBadRequestInvalidCredentials(t *testing.T) {
+	h := Signup(stubCreateUser{}, stubTokens{})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"email":"ada","password":"short"}`))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Len(t, got.Errors, 2)
+}
+
+func TestSignupConflict(t *testing.T) {
+	r := stubCreateUser{func(u User) (*User, error) {
+		return nil, ErrEmailTaken
+	}}
+	h := Signup(r, stubTokens{})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"email":"ada@example.com","password":"correct horse"}`))
+	h(c)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestSignupCreated(t *testing.T) {
+	var hash string
+	r := stubCreateUser{func(u User) (*User, error) {
+		hash = u.PasswordHash
+		return &User{ID: "user-1", Email: u.Email}, nil
+	}}
+	h := Signup(r, stubTokens{})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"email":"ada@example.com","password":"correct horse"}`))
+	h(c)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, auth.CheckPassword(hash, "correct horse"))
+	got := MustUnmarshal[Session](w.Body.Bytes())
+	assert.Equal(t, "token-for-user-1", got.Token)
+	assert.Equal(t, "ada@example.com", got.User.Email)
+	assert.NotContains(t, w.Body.String(), hash)
+}
+
+func TestLoginUnauthorized(t *testing.T) {
+	hash, _ := auth.HashPassword("correct horse")
+	r := stubGetUserByEmail{func(email string) (*User, error) {
+		if email == "ada@example.com" {
+			return &User{ID: "user-1", Email: email, PasswordHash: hash}, nil
+		}
+		return nil, nil
+	}}
+	h := Login(r, stubTokens{})
+	for _, body := range []string{
+		`{"email":"ada@example.com","password":"wrong horse"}`,
+		`{"email":"grace@example.com","password":"correct horse"}`,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+		h(c)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, body)
+	}
+}
+
+type stubGetAPIKeyByHash struct {
+	keys map[string]*APIKey
+}
+
+func (r stubGetAPIKeyByHash) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	return r.keys[hash], nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	userID := uuid.New()
+	app := gin.New()
+	app.GET("/", Authenticate(stubTokens{}, stubGetAPIKeyByHash{}), func(c *gin.Context) {
+		id, _ := UserIDFromContext(c.Request.Context())
+		c.String(http.StatusOK, id.String())
+	})
+	for header, want := range map[string]int{
+		"":                                    http.StatusUnauthorized,
+		"Basic abc":                           http.StatusUnauthorized,
+		"Bearer nonsense":                     http.StatusUnauthorized,
+		"Bearer token-for-not-a-uuid":         http.StatusUnauthorized,
+		"Bearer token-for-" + userID.String(): http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", header)
+		app.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, header)
+		if want == http.StatusOK {
+			assert.Equal(t, userID.String(), w.Body.String())
+		} else {
+			assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestAuthenticateWithAPIKey(t *testing.T) {
+	userID := uuid.New()
+	keys := stubGetAPIKeyByHash{map[string]*APIKey{
+		auth.HashAPIKey("todo_reader"): {ID: "key-1", UserID: userID.String(), Scopes: []Scope{ScopeTodosRead}},
+	}}
+	app := gin.New()
+	app.GET("/", Authenticate(stubTokens{}, keys), RequireScope(ScopeTodosRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	app.POST("/", Authenticate(stubTokens{}, keys), RequireScope(ScopeTodosWrite), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	for _, tc := range []struct {
+		method string
+		key    string
+		want   int
+	}{
+		{http.MethodGet, "todo_reader", http.StatusOK},
+		{http.MethodPost, "todo_reader", http.StatusForbidden},
+		{http.MethodGet, "todo_unknown", http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, "/", nil)
+		req.Header.Set("X-API-Key", tc.key)
+		app.ServeHTTP(w, req)
+		assert.Equal(t, tc.want, w.Code, tc)
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	. "todo-app/data"
+	"todo-app/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+type batch interface {
+	Batch(ctx context.Context, ops []TodoOperation, atomic bool) ([]TodoOperationResult, error)
+}
+
+type BatchResult struct {
+	Status int      `json:"status"`
+	Todo   *Todo    `json:"todo,omitempty"`
+	Error  *Problem `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+func batchResult(op TodoOperation, result TodoOperationResult) BatchResult {
+	switch {
+	case errors.Is(result.Err, ErrNotFound):
+		return BatchResult{Status: http.StatusNotFound, Error: notFound("todo " + op.ID + " does not exist")}
+	case errors.Is(result.Err, ErrBatchAborted):
+		return BatchResult{Status: http.StatusFailedDependency, Error: NewProblem(
+			http.StatusFailedDependency,
+			"batch-aborted",
+			"the operation was rolled back because another operation failed",
+		)}
+	case result.Err != nil:
+		p := ProblemFor(result.Err)
+		return BatchResult{Status: p.Status, Error: p}
+	case op.Op == OpCreate:
+		return BatchResult{Status: http.StatusCreated, Todo: result.Todo}
+	case op.Op == OpDelete:
+		return BatchResult{Status: http.StatusNoContent}
+	default:
+		return BatchResult{Status: http.StatusOK, Todo: result.Todo}
+	}
+}
+
+func BatchTodos(t batch) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var atomic bool
+		if value := c.Query("atomic"); value != "" {
+			var err error
+			if atomic, err = strconv.ParseBool(value); err != nil {
+				abort(c, invalidQuery(validation.Errors{{Field: "atomic", Detail: "must be true or false"}}))
+				return
+			}
+		}
+		var body TodoBatch
+		if err := bindJSON(c, &body); err != nil {
+			abort(c, err)
+			return
+		}
+		results, err := t.Batch(c.Request.Context(), body.Operations, atomic)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		response := BatchResponse{Committed: true, Results: make([]BatchResult, len(results))}
+		for i, result := range results {
+			response.Results[i] = batchResult(body.Operations[i], result)
+			if atomic && result.Err != nil {
+				response.Committed = false
+			}
+		}
+		status := http.StatusOK
+		if !response.Committed {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, response)
+	}
+}
+
+func CustomMethod(param string, h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Param(param) != ":"+param {
+			abort(c, notFound("no route matches "+c.Request.Method+" "+c.Request.URL.Path))
+			return
+		}
+		h(c)
+	}
+}
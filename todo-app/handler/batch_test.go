@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	. "todo-app/data"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubBatch struct {
+	stub func(ops []TodoOperation, atomic bool) ([]TodoOperationResult, error)
+}
+
+func (r stubBatch) Batch(ctx context.Context, ops []TodoOperation, atomic bool) ([]TodoOperationResult, error) {
+	return r.stub(ops, atomic)
+}
+
+func TestBatchTodosBadRequestInvalidOperations(t *testing.T) {
+	r := stubBatch{func(ops []TodoOperation, atomic bool) ([]TodoOperationResult, error) {
+		t.Fatal("Batch should not be called")
+		return nil, nil
+	}}
+	h := BatchTodos(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"operations":[{"op":"create","todo":{"task":""}},{"op":"update","todoId":"1"}]}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Len(t, got.Errors, 3)
+	assert.Equal(t, "operations[0].todo.task", got.Errors[0].Field)
+	assert.Equal(t, "operations[1].todoId", got.Errors[1].Field)
+	assert.Equal(t, "operations[1].todo", got.Errors[2].Field)
+}
+
+func TestBatchTodosBadRequestInvalidAtomic(t *testing.T) {
+	h := BatchTodos(stubBatch{})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/?atomic=maybe", nil)
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBatchTodosError(t *testing.T) {
+	r := stubBatch{func(ops []TodoOperation, atomic bool) ([]TodoOperationResult, error) {
+		return nil, errors.New("oops!")
+	}}
+	h := BatchTodos(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"operations":[{"op":"delete","todoId":"` + uuid.NewString() + `"}]}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestBatchTodosPartialSuccess(t *testing.T) {
+	var atomic bool
+	r := stubBatch{func(ops []TodoOperation, a bool) ([]TodoOperationResult, error) {
+		atomic = a
+		return []TodoOperationResult{
+			{Todo: &Todo{Task: "Learn Go"}},
+			{Err: ErrNotFound},
+		}, nil
+	}}
+	h := BatchTodos(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"operations":[{"op":"create","todo":{"task":"Learn Go"}},{"op":"delete","todoId":"` + uuid.NewString() + `"}]}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.False(t, atomic)
+	assert.Equal(t, http.StatusOK, w.Code)
+	got := MustUnmarshal[BatchResponse](w.Body.Bytes())
+	assert.True(t, got.Committed)
+	assert.Equal(t, http.StatusCreated, got.Results[0].Status)
+	assert.Equal(t, "Learn Go", got.Results[0].Todo.Task)
+	assert.Equal(t, http.StatusNotFound, got.Results[1].Status)
+	assert.Equal(t, "urn:todo-app:problem:not-found", got.Results[1].Error.Type)
+}
+
+func TestBatchTodosAtomicFailure(t *testing.T) {
+	r := stubBatch{func(ops []TodoOperation, atomic bool) ([]TodoOperationResult, error) {
+		return []TodoOperationResult{
+			{Err: ErrBatchAborted},
+			{Err: ErrNotFound},
+		}, nil
+	}}
+	h := BatchTodos(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"operations":[{"op":"create","todo":{"task":"Learn Go"}},{"op":"delete","todoId":"` + uuid.NewString() + `"}]}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/?atomic=true", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	got := MustUnmarshal[BatchResponse](w.Body.Bytes())
+	assert.False(t, got.Committed)
+	assert.Equal(t, http.StatusFailedDependency, got.Results[0].Status)
+	assert.Equal(t, http.StatusNotFound, got.Results[1].Status)
+}
+
+func TestCustomMethod(t *testing.T) {
+	app := gin.New()
+	app.POST("/todos:batch", CustomMethod("batch", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}))
+	for path, want := range map[string]int{
+		"/todos:batch": http.StatusOK,
+		"/todos:other": http.StatusNotFound,
+		"/todosbatch":  http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		assert.Equal(t, want, w.Code, path)
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	. "todo-app/data"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ETag(todo *Todo) string {
+	return `"` + strconv.FormatInt(todo.UpdatedAt.UnixMicro(), 36) + `"`
+}
+
+func parseIfMatch(c *gin.Context) (*time.Time, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	tag, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		tag, ok = strings.CutSuffix(tag, `"`)
+	}
+	if !ok {
+		return nil, ErrPreconditionFailed
+	}
+	micros, err := strconv.ParseInt(tag, 36, 64)
+	if err != nil {
+		return nil, ErrPreconditionFailed
+	}
+	version := time.UnixMicro(micros)
+	return &version, nil
+}
+
+func writeTodo(c *gin.Context, status int, todo *Todo) {
+	c.Header("ETag", ETag(todo))
+	c.JSON(status, todo)
+}
+
+func RequireIfMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("If-Match") == "" {
+			abort(c, NewProblem(
+				http.StatusPreconditionRequired,
+				"precondition-required",
+				"the If-Match header is required to modify this resource",
+			))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"write-to-stderr/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ReadyTimeout = 2 * time.Second
+
+type Check func(ctx context.Context) error
+
+type Health struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func Healthz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, Health{Status: "ok"})
+	}
+}
+
+func Readyz(checks map[string]Check) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), ReadyTimeout)
+		defer cancel()
+		health := Health{Status: "ok", Checks: make(map[string]string, len(checks))}
+		var failed []string
+		for name, check := range checks {
+			if err := check(ctx); err != nil {
+				logging.FromContext(c.Request.Context()).Error("readiness check failed", "check", name, "err", err)
+				failed = append(failed, name)
+				continue
+			}
+			health.Checks[name] = "ok"
+		}
+		if len(failed) > 0 {
+			sort.Strings(failed)
+			abort(c, NewProblem(http.StatusServiceUnavailable, "not-ready", "failing checks: "+strings.Join(failed, ", ")))
+			return
+		}
+		c.JSON(http.StatusOK, health)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthz(t *testing.T) {
+	app := gin.New()
+	app.GET("/healthz", Healthz())
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		body   string
+	}{
+		{"ready", nil, http.StatusOK, `{"status":"ok","checks":{"postgres":"ok"}}`},
+		{"not ready", errors.New("connection refused"), http.StatusServiceUnavailable, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := gin.New()
+			app.GET("/readyz", Readyz(map[string]Check{
+				"postgres": func(ctx context.Context) error {
+					_, ok := ctx.Deadline()
+					assert.True(t, ok)
+					return test.err
+				},
+			}))
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, test.status, w.Code)
+			if test.err == nil {
+				assert.JSONEq(t, test.body, w.Body.String())
+				return
+			}
+			var p Problem
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &p))
+			assert.Equal(t, "urn:todo-app:problem:not-ready", p.Type)
+			assert.Equal(t, "failing checks: postgres", p.Detail)
+			assert.NotContains(t, w.Body.String(), "connection refused")
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	. "todo-app/data"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func listNotFound(id uuid.UUID) *Problem {
+	return notFound(fmt.Sprintf("list %s does not exist", id))
+}
+
+type createList interface {
+	CreateList(ctx context.Context, l List) (*List, error)
+}
+
+func CreateList(l createList) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var list List
+		if err := bindJSON(c, &list); err != nil {
+			abort(c, err)
+			return
+		}
+		created, err := l.CreateList(c.Request.Context(), list)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, created)
+	}
+}
+
+type getLists interface {
+	GetLists(ctx context.Context) ([]List, error)
+}
+
+func GetLists(l getLists) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		lists, err := l.GetLists(c.Request.Context())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"lists": lists})
+	}
+}
+
+type getListByID interface {
+	GetListByID(ctx context.Context, id uuid.UUID) (*List, error)
+}
+
+func findList(c *gin.Context, l getListByID) (*List, error) {
+	id, err := parseID(c)
+	if err != nil {
+		return nil, err
+	}
+	list, err := l.GetListByID(c.Request.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, listNotFound(id)
+	}
+	return list, nil
+}
+
+func GetListByID(l getListByID) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		list, err := findList(c, l)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+type updateListByID interface {
+	UpdateListByID(ctx context.Context, id uuid.UUID, l List) (*List, error)
+}
+
+func UpdateListByID(l updateListByID) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		id, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		var list List
+		if err := bindJSON(c, &list); err != nil {
+			abort(c, err)
+			return
+		}
+		updated, err := l.UpdateListByID(c.Request.Context(), id, list)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if updated == nil {
+			abort(c, listNotFound(id))
+			return
+		}
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+type deleteListByID interface {
+	DeleteListByID(ctx context.Context, id uuid.UUID) (*List, error)
+}
+
+func DeleteListByID(l deleteListByID) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		id, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		deleted, err := l.DeleteListByID(c.Request.Context(), id)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if deleted == nil {
+			abort(c, listNotFound(id))
+			return
+		}
+		c.Status(http.StatusNoContent)
+		c.Writer.WriteHeaderNow()
+	}
+}
+
+func GetListTodos(l getListByID, t getAll) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		list, err := findList(c, l)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		q, err := parseTodoQuery(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		id := uuid.MustParse(list.ID)
+		q.ListID = &id
+		page, err := t.GetAll(c.Request.Context(), q)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+func CreateListTodo(l getListByID, t createOne) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		list, err := findList(c, l)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		var todo Todo
+		if err := bindJSON(c, &todo); err != nil {
+			abort(c, err)
+			return
+		}
+		todo.ListID = &list.ID
+		created, err := t.CreateOne(c.Request.Context(), todo)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		writeTodo(c, http.StatusCreated, created)
+	}
+}
+
+type getTags interface {
+	GetTags(ctx context.Context) ([]Tag, error)
+}
+
+func GetTags(t getTags) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		tags, err := t.GetTags(c.Request.Context())
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"tags": tags})
+	}
+}
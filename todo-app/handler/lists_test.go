@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	. "todo-app/data"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubGetListByID struct {
+	stub func(id uuid.UUID) (*List, error)
+}
+
+func (r stubGetListByID) GetListByID(ctx context.Context, id uuid.UUID) (*List, error) {
+	return r.stub(id)
+}
+
+func TestCreateListRejectsBlankName(t *testing.T) {
+	h := CreateList(nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"name":" "}`))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "name", got.Errors[0].Field)
+}
+
+func TestGetListTodosNotFound(t *testing.T) {
+	l := stubGetListByID{func(id uuid.UUID) (*List, error) {
+		return nil, nil
+	}}
+	h := GetListTodos(l, nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetListTodosFiltersByList(t *testing.T) {
+	list := List{ID: uuid.NewString(), Name: "Work"}
+	l := stubGetListByID{func(id uuid.UUID) (*List, error) {
+		return &list, nil
+	}}
+	var got TodoQuery
+	r := StubGetAll{func(q TodoQuery) (*TodoPage, error) {
+		got = q
+		return &TodoPage{Todos: make([]Todo, 0)}, nil
+	}}
+	h := GetListTodos(l, r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: list.ID})
+	c.Request = httptest.NewRequest(http.MethodGet, "/?tag=backend", nil)
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, list.ID, got.ListID.String())
+	assert.Equal(t, "backend", got.Tag)
+}
+
+func TestCreateListTodoFilesTheTodo(t *testing.T) {
+	list := List{ID: uuid.NewString(), Name: "Work"}
+	l := stubGetListByID{func(id uuid.UUID) (*List, error) {
+		return &list, nil
+	}}
+	var got Todo
+	r := StubCreateOne{func(todo Todo) (*Todo, error) {
+		got = todo
+		todo.ID = uuid.NewString()
+		return &todo, nil
+	}}
+	h := CreateListTodo(l, r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: list.ID})
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"task":"Fix the build"}`))
+	h(c)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, list.ID, *got.ListID)
+}
+
+func TestListNotFoundForTodoIsAValidationError(t *testing.T) {
+	r := StubCreateOne{func(todo Todo) (*Todo, error) {
+		return nil, ErrListNotFound
+	}}
+	h := CreateOneTodo(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"task":"Fix the build","listId":"` + uuid.NewString() + `"}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "listId", got.Errors[0].Field)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"requestid"
+	"runtime/debug"
+	"time"
+	"write-to-stderr/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := requestid.Resolve(c.GetHeader(requestid.Header))
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func RequestLogger(logger *logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestLogger := logger
+		if id, ok := requestid.FromContext(c.Request.Context()); ok {
+			requestLogger = logger.With("requestId", id)
+		}
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), requestLogger))
+		c.Next()
+		status := c.Writer.Status()
+		level := logging.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = logging.LevelError
+		case status >= http.StatusBadRequest:
+			level = logging.LevelWarn
+		}
+		keyvals := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"duration", time.Since(start),
+			"bytes", c.Writer.Size(),
+			"clientIp", c.ClientIP(),
+		}
+		if err := c.Errors.Last(); err != nil {
+			keyvals = append(keyvals, "err", err.Err)
+		}
+		requestLogger.Log(level, "request", keyvals...)
+	}
+}
+
+func Recover() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			logging.FromContext(c.Request.Context()).Error("panic", "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+			abort(c, fmt.Errorf("panic: %v", p))
+		}()
+		c.Next()
+	}
+}
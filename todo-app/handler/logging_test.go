@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"requestid"
+	"testing"
+	"write-to-stderr/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+		assert.Nil(t, dec.Decode(&line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRequestLogger(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	logger := logging.New(logging.WithOutput(&stdout, &stderr), logging.WithEncoder(logging.JSONEncoder{}))
+	app := gin.New()
+	app.Use(RequestID(), RequestLogger(logger))
+	app.GET("/todos/:id", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Info("handling")
+		c.Status(http.StatusOK)
+	})
+	app.GET("/fail", func(c *gin.Context) {
+		abort(c, errors.New("database is down"))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/todos/1", nil)
+	req.Header.Set(requestid.Header, "abc-123")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, "abc-123", w.Header().Get(requestid.Header))
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	generated := w.Header().Get(requestid.Header)
+	assert.Len(t, generated, 32)
+
+	info := decodeLines(t, &stdout)
+	assert.Len(t, info, 2)
+	assert.Equal(t, "handling", info[0]["msg"])
+	assert.Equal(t, "abc-123", info[0]["requestId"])
+	assert.Equal(t, "request", info[1]["msg"])
+	assert.Equal(t, "abc-123", info[1]["requestId"])
+	assert.Equal(t, "/todos/:id", info[1]["route"])
+	assert.Equal(t, "/todos/1", info[1]["path"])
+	assert.Equal(t, float64(http.StatusOK), info[1]["status"])
+
+	errs := decodeLines(t, &stderr)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "error", errs[0]["level"])
+	assert.Equal(t, generated, errs[0]["requestId"])
+	assert.Equal(t, float64(http.StatusInternalServerError), errs[0]["status"])
+	assert.Equal(t, "database is down", errs[0]["err"])
+}
+
+func TestRecover(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	logger := logging.New(logging.WithOutput(&stdout, &stderr), logging.WithEncoder(logging.JSONEncoder{}))
+	app := gin.New()
+	app.Use(RequestLogger(logger), Recover())
+	app.GET("/", func(c *gin.Context) {
+		panic("oh no")
+	})
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+	errs := decodeLines(t, &stderr)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "panic", errs[0]["msg"])
+	assert.Equal(t, "oh no", errs[0]["panic"])
+	assert.Contains(t, errs[0]["stack"], "runtime/debug.Stack")
+	assert.Equal(t, "panic: oh no", errs[1]["err"])
+}
+
+func TestRequestID(t *testing.T) {
+	var id string
+	app := gin.New()
+	app.Use(RequestID())
+	app.GET("/", func(c *gin.Context) {
+		id, _ = requestid.FromContext(c.Request.Context())
+		abort(c, notFound("nothing here"))
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.Header, "abc-123")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, "abc-123", id)
+	assert.Equal(t, "abc-123", w.Header().Get(requestid.Header))
+	var p Problem
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, "abc-123", p.RequestID)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.Header, "not valid")
+	app.ServeHTTP(w, req)
+	assert.NotEqual(t, "not valid", id)
+	assert.Equal(t, id, w.Header().Get(requestid.Header))
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"requestid"
+	. "todo-app/data"
+	"todo-app/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// StatusClientClosedRequest is nginx's status for a request the client gave
+// up on; nobody sees the response, but logs and metrics do.
+const StatusClientClosedRequest = 499
+
+// MaxBodyBytes caps JSON request bodies; a full batch fits well within it.
+const MaxBodyBytes = 1 << 20
+
+func statusText(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
+
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Errors    validation.Errors `json:"errors,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+func NewProblem(status int, kind string, detail string) *Problem {
+	return &Problem{
+		Type:   "urn:todo-app:problem:" + kind,
+		Title:  statusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func invalidID(param string) *Problem {
+	return NewProblem(http.StatusNotFound, "invalid-id", param+" must be a UUID")
+}
+
+func notFound(detail string) *Problem {
+	return NewProblem(http.StatusNotFound, "not-found", detail)
+}
+
+func malformedJSON(err error) *Problem {
+	return NewProblem(http.StatusBadRequest, "malformed-json", err.Error())
+}
+
+func bodyTooLarge(limit int64) *Problem {
+	return NewProblem(http.StatusRequestEntityTooLarge, "body-too-large", fmt.Sprintf("request body must not exceed %d bytes", limit))
+}
+
+func invalidQuery(errs validation.Errors) *Problem {
+	p := NewProblem(http.StatusBadRequest, "invalid-query", "one or more query parameters are invalid")
+	p.Errors = errs
+	return p
+}
+
+func validationFailed(errs validation.Errors) *Problem {
+	p := NewProblem(http.StatusBadRequest, "validation-failed", "one or more fields are invalid")
+	p.Errors = errs
+	return p
+}
+
+func ProblemFor(err error) *Problem {
+	var p *Problem
+	var errs validation.Errors
+	switch {
+	case errors.As(err, &p):
+		return p
+	case errors.As(err, &errs):
+		return validationFailed(errs)
+	case errors.Is(err, ErrInvalidCursor):
+		return invalidQuery(validation.Errors{{Field: "cursor", Detail: err.Error()}})
+	case errors.Is(err, ErrListNotFound):
+		return validationFailed(validation.Errors{{Field: "listId", Detail: "must reference an existing list"}})
+	case errors.Is(err, ErrParentNotFound):
+		return validationFailed(validation.Errors{{Field: "parentTodoId", Detail: "must reference an existing todo"}})
+	case errors.Is(err, ErrCyclicParent):
+		return validationFailed(validation.Errors{{Field: "parentTodoId", Detail: "must not be the todo or one of its subtasks"}})
+	case errors.Is(err, ErrEventNotFound):
+		return validationFailed(validation.Errors{{Field: "eventId", Detail: "must reference an event in the todo's history"}})
+	case errors.Is(err, ErrParentChanged):
+		problem := NewProblem(http.StatusUnprocessableEntity, "parent-changed", "use POST /v1/todos/{id}/move to change a todo's parent")
+		problem.Errors = validation.Errors{{Field: "parentTodoId", Detail: "must match the todo's current parent"}}
+		return problem
+	case errors.Is(err, ErrRevertCrossesMove):
+		return NewProblem(http.StatusConflict, "revert-crosses-move", "the todo has been moved since that event; move it back first")
+	case errors.Is(err, ErrPreconditionFailed):
+		return NewProblem(http.StatusPreconditionFailed, "precondition-failed", "the todo has been modified since it was last read")
+	case errors.Is(err, ErrUnauthenticated):
+		return NewProblem(http.StatusUnauthorized, "unauthenticated", "a valid bearer token is required")
+	case errors.Is(err, ErrInvalidCredentials):
+		return NewProblem(http.StatusUnauthorized, "invalid-credentials", "the email or password is incorrect")
+	case errors.Is(err, ErrEmailTaken):
+		return NewProblem(http.StatusConflict, "email-taken", "an account with that email already exists")
+	case errors.Is(err, context.Canceled):
+		return NewProblem(StatusClientClosedRequest, "canceled", "the request was canceled by the client")
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewProblem(http.StatusGatewayTimeout, "timeout", "the request took too long to complete")
+	default:
+		return NewProblem(http.StatusInternalServerError, "internal-error", "an unexpected error occurred")
+	}
+}
+
+func bindJSON(c *gin.Context, v any) error {
+	if c.Request == nil || c.Request.Body == nil {
+		return malformedJSON(validation.ErrEmptyBody)
+	}
+	err := validation.DecodeJSON(http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodyBytes), v)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return bodyTooLarge(tooLarge.Limit)
+	}
+	var errs validation.Errors
+	if errors.As(err, &errs) {
+		return validationFailed(errs)
+	}
+	if err != nil {
+		return malformedJSON(err)
+	}
+	return nil
+}
+
+func requestIDOf(c *gin.Context) (string, bool) {
+	if c.Request == nil {
+		return "", false
+	}
+	return requestid.FromContext(c.Request.Context())
+}
+
+func writeProblem(c *gin.Context, p *Problem) {
+	if id, ok := requestIDOf(c); ok {
+		withID := *p
+		withID.RequestID = id
+		p = &withID
+	}
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+func abort(c *gin.Context, err error) {
+	c.Error(err)
+	writeProblem(c, ProblemFor(err))
+}
+
+func Problems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		writeProblem(c, ProblemFor(c.Errors.Last().Err))
+	}
+}
+
+func NoRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Error(notFound("no route matches " + c.Request.Method + " " + c.Request.URL.Path))
+	}
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	. "todo-app/data"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemForProblem(t *testing.T) {
+	want := NewProblem(http.StatusConflict, "conflict", "oops!")
+	got := ProblemFor(fmt.Errorf("wrapped: %w", want))
+	assert.Equal(t, want, got)
+}
+
+func TestProblemForRepositoryErrors(t *testing.T) {
+	for err, status := range map[error]int{
+		ErrInvalidCursor: http.StatusBadRequest,
+		fmt.Errorf("querying: %w", context.DeadlineExceeded): http.StatusGatewayTimeout,
+		fmt.Errorf("querying: %w", context.Canceled):         StatusClientClosedRequest,
+		errors.New("oops!"): http.StatusInternalServerError,
+	} {
+		p := ProblemFor(err)
+		assert.Equal(t, status, p.Status, err.Error())
+		assert.Equal(t, statusText(status), p.Title)
+	}
+}
+
+func TestProblemForHidesInternalErrors(t *testing.T) {
+	p := ProblemFor(errors.New("pq: password authentication failed"))
+	assert.NotContains(t, p.Detail, "pq")
+}
+
+func TestProblemsMiddlewareRendersUnhandledErrors(t *testing.T) {
+	app := gin.New()
+	app.Use(Problems())
+	app.GET("/", func(c *gin.Context) {
+		c.Error(errors.New("oops!"))
+	})
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:internal-error", got.Type)
+}
+
+func TestProblemsMiddlewareLeavesWrittenResponses(t *testing.T) {
+	app := gin.New()
+	app.Use(Problems())
+	app.GET("/", func(c *gin.Context) {
+		c.Error(errors.New("oops!"))
+		c.String(http.StatusOK, "ok")
+	})
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestNoRouteRendersProblem(t *testing.T) {
+	app := gin.New()
+	app.Use(Problems())
+	app.NoRoute(NoRoute())
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:not-found", got.Type)
+}
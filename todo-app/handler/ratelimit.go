@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"todo-app/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type limiter interface {
+	Allow(key string) ratelimit.Decision
+}
+
+type peekLimiter interface {
+	limiter
+	Peek(key string) ratelimit.Decision
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func rateLimit(c *gin.Context, l limiter, key string) {
+	d := l.Allow(key)
+	c.Header("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	c.Header("X-RateLimit-Reset", ceilSeconds(d.Reset))
+	if !d.Allowed {
+		rateLimited(c, d)
+		return
+	}
+	c.Next()
+}
+
+func rateLimited(c *gin.Context, d ratelimit.Decision) {
+	c.Header("Retry-After", ceilSeconds(d.RetryAfter))
+	abort(c, NewProblem(http.StatusTooManyRequests, "rate-limited", "too many requests, retry after "+ceilSeconds(d.RetryAfter)+"s"))
+}
+
+// RateLimitFailures goes before Authenticate and spends a client address's
+// tokens only on rejected credentials, so valid keys sharing an address are
+// left to their own limits.
+func RateLimitFailures(l peekLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if d := l.Peek(key); !d.Allowed {
+			rateLimited(c, d)
+			return
+		}
+		c.Next()
+		if c.Writer.Status() == http.StatusUnauthorized {
+			l.Allow(key)
+		}
+	}
+}
+
+func RateLimitByIP(l limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rateLimit(c, l, "ip:"+c.ClientIP())
+	}
+}
+
+func RateLimit(l limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if id := c.GetString(apiKeyIDKey); id != "" {
+			key = "key:" + id
+		}
+		rateLimit(c, l, key)
+	}
+}
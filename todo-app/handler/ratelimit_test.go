@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo-app/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubLimiter struct {
+	keys     []string
+	decision ratelimit.Decision
+}
+
+func (l *stubLimiter) Allow(key string) ratelimit.Decision {
+	l.keys = append(l.keys, key)
+	return l.decision
+}
+
+func TestRateLimitAllowed(t *testing.T) {
+	l := &stubLimiter{decision: ratelimit.Decision{Allowed: true, Limit: 10, Remaining: 9, Reset: 100 * time.Millisecond}}
+	app := gin.New()
+	app.GET("/", RateLimit(l), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	app.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"ip:10.0.0.1"}, l.keys)
+	assert.Equal(t, "10", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "9", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+}
+
+func TestRateLimitKeyedByAPIKey(t *testing.T) {
+	l := &stubLimiter{decision: ratelimit.Decision{Limit: 10, RetryAfter: 1500 * time.Millisecond}}
+	app := gin.New()
+	app.GET("/", func(c *gin.Context) {
+		c.Set(apiKeyIDKey, "key-1")
+	}, RateLimit(l), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, []string{"key:key-1"}, l.keys)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+}
+
+func TestRateLimitByIPIgnoresAPIKey(t *testing.T) {
+	l := &stubLimiter{decision: ratelimit.Decision{Allowed: true, Limit: 10}}
+	app := gin.New()
+	app.GET("/", func(c *gin.Context) {
+		c.Set(apiKeyIDKey, "key-1")
+	}, RateLimitByIP(l), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	app.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"ip:10.0.0.1"}, l.keys)
+}
+
+func (l *stubLimiter) Peek(key string) ratelimit.Decision {
+	return l.decision
+}
+
+func TestRateLimitFailuresSpendsOnlyOnRejectedCredentials(t *testing.T) {
+	l := &stubLimiter{decision: ratelimit.Decision{Allowed: true, Limit: 10}}
+	app := gin.New()
+	app.GET("/ok", RateLimitFailures(l), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	app.GET("/denied", RateLimitFailures(l), func(c *gin.Context) {
+		c.Status(http.StatusUnauthorized)
+	})
+	for _, path := range []string{"/ok", "/denied"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		app.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, []string{"ip:10.0.0.1"}, l.keys)
+	l.decision = ratelimit.Decision{Limit: 10, RetryAfter: time.Second}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
@@ -1,116 +1,427 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 	. "todo-app/data"
+	"todo-app/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+func parseID(c *gin.Context) (uuid.UUID, error) {
+	id, ok := c.Params.Get("id")
+	if !ok {
+		return uuid.Nil, invalidID("id")
+	}
+	todoId, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, invalidID("id")
+	}
+	return todoId, nil
+}
+
+func todoNotFound(id uuid.UUID) *Problem {
+	return notFound(fmt.Sprintf("todo %s does not exist", id))
+}
+
 type getAll interface {
-	GetAll() ([]Todo, error)
+	GetAll(ctx context.Context, q TodoQuery) (*TodoPage, error)
+}
+
+func parseTodoQuery(c *gin.Context) (TodoQuery, error) {
+	var q TodoQuery
+	var errs validation.Errors
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			errs = append(errs, validation.FieldError{
+				Field:  "limit",
+				Detail: fmt.Sprintf("must be an integer from 1 to %d", MaxLimit),
+			})
+		}
+		q.Limit = n
+	}
+	if isCompleted := c.Query("isCompleted"); isCompleted != "" {
+		b, err := strconv.ParseBool(isCompleted)
+		if err != nil {
+			errs = append(errs, validation.FieldError{Field: "isCompleted", Detail: "must be true or false"})
+		}
+		q.IsCompleted = &b
+	}
+	if overdue := c.Query("overdue"); overdue != "" {
+		b, err := strconv.ParseBool(overdue)
+		if err != nil {
+			errs = append(errs, validation.FieldError{Field: "overdue", Detail: "must be true or false"})
+		}
+		q.Overdue = &b
+	}
+	sort, err := ParseTodoSort(c.Query("sort"))
+	if err != nil {
+		errs = append(errs, validation.FieldError{Field: "sort", Detail: err.Error()})
+	}
+	q.Sort = sort
+	if cursor := c.Query("cursor"); cursor != "" {
+		if q.Cursor, err = DecodeTodoCursor(cursor); err != nil {
+			errs = append(errs, validation.FieldError{Field: "cursor", Detail: err.Error()})
+		}
+	}
+	q.Tag = c.Query("tag")
+	q.Search = c.Query("q")
+	if len(errs) > 0 {
+		return q, invalidQuery(errs)
+	}
+	return q, nil
 }
 
 func GetAllTodos(t getAll) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		todos, err := t.GetAll()
+		q, err := parseTodoQuery(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		page, err := t.GetAll(c.Request.Context(), q)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
+			abort(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, todos)
+		c.JSON(http.StatusOK, page)
 	}
 }
 
 type getOneByID interface {
-	GetOneByID(id uuid.UUID) (*Todo, error)
+	GetOneByID(ctx context.Context, id uuid.UUID) (*Todo, error)
 }
 
 func GetOneTodoByID(t getOneByID) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		id, ok := c.Params.Get("id")
-		if !ok {
-			c.AbortWithStatus(http.StatusNotFound)
-			return
-		}
-		todoId, err := uuid.Parse(id)
+		todoId, err := parseID(c)
 		if err != nil {
-			c.AbortWithStatus(http.StatusNotFound)
+			abort(c, err)
 			return
 		}
-		todo, err := t.GetOneByID(todoId)
+		todo, err := t.GetOneByID(c.Request.Context(), todoId)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
+			abort(c, err)
 			return
 		}
 		if todo == nil {
-			c.AbortWithStatus(http.StatusNotFound)
+			abort(c, todoNotFound(todoId))
 			return
 		}
-		c.JSON(http.StatusOK, todo)
+		writeTodo(c, http.StatusOK, todo)
 	}
 }
 
 type createOne interface {
-	CreateOne(todo Todo) (*Todo, error)
+	CreateOne(ctx context.Context, todo Todo) (*Todo, error)
 }
 
 func CreateOneTodo(t createOne) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var todo Todo
-		if err := c.ShouldBindJSON(&todo); err != nil {
-			c.AbortWithStatus(http.StatusBadRequest)
-			return
-		}
-		if &todo.Task == nil || strings.TrimSpace(todo.Task) == "" {
-			c.AbortWithStatus(http.StatusBadRequest)
+		if err := bindJSON(c, &todo); err != nil {
+			abort(c, err)
 			return
 		}
-		created, err := t.CreateOne(todo)
+		created, err := t.CreateOne(c.Request.Context(), todo)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
+			abort(c, err)
 			return
 		}
-		c.JSON(http.StatusCreated, created)
+		writeTodo(c, http.StatusCreated, created)
 	}
 }
 
 type updateOneByID interface {
-	UpdateOneByID(id uuid.UUID, todo Todo) (*Todo, error)
+	UpdateOneByID(ctx context.Context, id uuid.UUID, todo Todo, ifMatch *time.Time) (*Todo, error)
 }
 
 func UpdateOneTodoByID(t updateOneByID) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		id, ok := c.Params.Get("id")
-		if !ok {
-			c.AbortWithStatus(http.StatusNotFound)
-			return
-		}
-		todoId, err := uuid.Parse(id)
+		todoId, err := parseID(c)
 		if err != nil {
-			c.AbortWithStatus(http.StatusNotFound)
+			abort(c, err)
 			return
 		}
 		var todo Todo
-		if err := c.ShouldBindJSON(&todo); err != nil {
-			c.AbortWithStatus(http.StatusBadRequest)
+		if err := bindJSON(c, &todo); err != nil {
+			abort(c, err)
 			return
 		}
-		if &todo.Task == nil || strings.TrimSpace(todo.Task) == "" {
-			c.AbortWithStatus(http.StatusBadRequest)
+		ifMatch, err := parseIfMatch(c)
+		if err != nil {
+			abort(c, err)
 			return
 		}
-		updated, err := t.UpdateOneByID(todoId, todo)
+		updated, err := t.UpdateOneByID(c.Request.Context(), todoId, todo, ifMatch)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
+			abort(c, err)
 			return
 		}
 		if updated == nil {
-			c.AbortWithStatus(http.StatusNotFound)
+			abort(c, todoNotFound(todoId))
+			return
+		}
+		writeTodo(c, http.StatusOK, updated)
+	}
+}
+
+type patchOneByID interface {
+	PatchOneByID(ctx context.Context, id uuid.UUID, patch TodoPatch, ifMatch *time.Time) (*Todo, error)
+}
+
+func PatchOneTodoByID(t patchOneByID) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		todoId, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		var patch TodoPatch
+		if err := bindJSON(c, &patch); err != nil {
+			abort(c, err)
+			return
+		}
+		ifMatch, err := parseIfMatch(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		patched, err := t.PatchOneByID(c.Request.Context(), todoId, patch, ifMatch)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if patched == nil {
+			abort(c, todoNotFound(todoId))
+			return
+		}
+		writeTodo(c, http.StatusOK, patched)
+	}
+}
+
+type deleteOneByID interface {
+	DeleteOneByID(ctx context.Context, id uuid.UUID) (*Todo, error)
+}
+
+func DeleteOneTodoByID(t deleteOneByID) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		todoId, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		deleted, err := t.DeleteOneByID(c.Request.Context(), todoId)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if deleted == nil {
+			abort(c, todoNotFound(todoId))
+			return
+		}
+		c.Status(http.StatusNoContent)
+		c.Writer.WriteHeaderNow()
+	}
+}
+
+type restoreOneByID interface {
+	RestoreOneByID(ctx context.Context, id uuid.UUID) (*Todo, error)
+}
+
+func RestoreOneTodoByID(t restoreOneByID) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		todoId, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		restored, err := t.RestoreOneByID(c.Request.Context(), todoId)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if restored == nil {
+			abort(c, todoNotFound(todoId))
+			return
+		}
+		writeTodo(c, http.StatusOK, restored)
+	}
+}
+
+type moveOneByID interface {
+	MoveOneByID(ctx context.Context, id uuid.UUID, m TodoMove) (*Todo, error)
+}
+
+func siblingNotFound(m TodoMove) *Problem {
+	field := "afterTodoId"
+	if m.Before != nil {
+		field = "beforeTodoId"
+	}
+	return validationFailed(validation.Errors{{Field: field, Detail: "must reference a todo with the same parent"}})
+}
+
+func MoveOneTodoByID(t moveOneByID) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		todoId, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		var move TodoMove
+		if err := bindJSON(c, &move); err != nil {
+			abort(c, err)
+			return
+		}
+		moved, err := t.MoveOneByID(c.Request.Context(), todoId, move)
+		if errors.Is(err, ErrSiblingNotFound) {
+			abort(c, siblingNotFound(move))
+			return
+		}
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if moved == nil {
+			abort(c, todoNotFound(todoId))
+			return
+		}
+		writeTodo(c, http.StatusOK, moved)
+	}
+}
+
+func GetSubtasks(parents getOneByID, t getAll) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		todoId, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		parent, err := parents.GetOneByID(c.Request.Context(), todoId)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if parent == nil {
+			abort(c, todoNotFound(todoId))
+			return
+		}
+		q, err := parseTodoQuery(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if c.Query("sort") == "" {
+			q.Sort = SortPosition
+		}
+		q.ParentID = &todoId
+		page, err := t.GetAll(c.Request.Context(), q)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+type search interface {
+	Search(ctx context.Context, q TodoSearch) ([]TodoSearchResult, error)
+}
+
+func parseTodoSearch(c *gin.Context) (TodoSearch, error) {
+	q := TodoSearch{Query: c.Query("q")}
+	var errs validation.Errors
+	if err := validation.Required("q", q.Query); err != nil {
+		errs = append(errs, *err)
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			errs = append(errs, validation.FieldError{
+				Field:  "limit",
+				Detail: fmt.Sprintf("must be an integer from 1 to %d", MaxLimit),
+			})
+		}
+		q.Limit = n
+	}
+	if len(errs) > 0 {
+		return q, invalidQuery(errs)
+	}
+	return q, nil
+}
+
+func SearchTodos(t search) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		q, err := parseTodoSearch(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		results, err := t.Search(c.Request.Context(), q)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, TodoSearchPage{Results: results})
+	}
+}
+
+type getHistory interface {
+	GetHistory(ctx context.Context, id uuid.UUID) ([]TodoEvent, error)
+}
+
+func GetTodoHistory(t getHistory) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		todoId, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		events, err := t.GetHistory(c.Request.Context(), todoId)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if len(events) == 0 {
+			abort(c, todoNotFound(todoId))
+			return
+		}
+		c.JSON(http.StatusOK, TodoHistory{Events: events})
+	}
+}
+
+type revertOneByID interface {
+	RevertOneByID(ctx context.Context, id uuid.UUID, eventID int64) (*Todo, error)
+}
+
+func RevertOneTodoByID(t revertOneByID) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		todoId, err := parseID(c)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		var revert TodoRevert
+		if err := bindJSON(c, &revert); err != nil {
+			abort(c, err)
+			return
+		}
+		reverted, err := t.RevertOneByID(c.Request.Context(), todoId, revert.EventID)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		if reverted == nil {
+			abort(c, todoNotFound(todoId))
 			return
 		}
-		c.JSON(http.StatusOK, updated)
+		writeTodo(c, http.StatusOK, reverted)
 	}
 }
@@ -2,12 +2,17 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 	. "todo-app/data"
+	"todo-app/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -27,57 +32,123 @@ func MustUnmarshal[T any](data []byte) T {
 }
 
 type StubGetAll struct {
-	stub func() ([]Todo, error)
+	stub func(q TodoQuery) (*TodoPage, error)
 }
 
-func (r StubGetAll) GetAll() ([]Todo, error) {
-	return r.stub()
+func (r StubGetAll) GetAll(ctx context.Context, q TodoQuery) (*TodoPage, error) {
+	return r.stub(q)
 }
 
 func TestGetAllTodosError(t *testing.T) {
-	r := StubGetAll{func() ([]Todo, error) {
+	r := StubGetAll{func(q TodoQuery) (*TodoPage, error) {
 		return nil, errors.New("oops!")
 	}}
 	h := GetAllTodos(r)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
 	h(c)
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
 func TestGetAllTodosEmpty(t *testing.T) {
-	want := make([]Todo, 0)
-	r := StubGetAll{func() ([]Todo, error) {
-		return want, nil
+	want := TodoPage{Todos: make([]Todo, 0)}
+	r := StubGetAll{func(q TodoQuery) (*TodoPage, error) {
+		return &want, nil
 	}}
 	h := GetAllTodos(r)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
 	h(c)
 	assert.Equal(t, http.StatusOK, w.Code)
-	got := MustUnmarshal[[]Todo](w.Body.Bytes())
+	got := MustUnmarshal[TodoPage](w.Body.Bytes())
 	assert.Equal(t, want, got)
 }
 
 func TestGetAllTodosPopulated(t *testing.T) {
-	want := []Todo{{Task: "Learn Go"}, {Task: "Accept Go"}}
-	r := StubGetAll{func() ([]Todo, error) {
-		return want, nil
+	next := "next"
+	want := TodoPage{Todos: []Todo{{Task: "Learn Go"}, {Task: "Accept Go"}}, NextCursor: &next}
+	r := StubGetAll{func(q TodoQuery) (*TodoPage, error) {
+		return &want, nil
 	}}
 	h := GetAllTodos(r)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
 	h(c)
 	assert.Equal(t, http.StatusOK, w.Code)
-	got := MustUnmarshal[[]Todo](w.Body.Bytes())
+	got := MustUnmarshal[TodoPage](w.Body.Bytes())
 	assert.Equal(t, want, got)
 }
 
+func TestGetAllTodosParsesQuery(t *testing.T) {
+	cursor := NewTodoCursor(SortUpdatedAtDesc, Todo{ID: uuid.NewString(), UpdatedAt: time.Now().UTC()})
+	var got TodoQuery
+	r := StubGetAll{func(q TodoQuery) (*TodoPage, error) {
+		got = q
+		return &TodoPage{Todos: make([]Todo, 0)}, nil
+	}}
+	h := GetAllTodos(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(
+		http.MethodGet,
+		"/?limit=5&isCompleted=true&overdue=false&q=go&sort=-updatedAt&cursor="+cursor.Encode(),
+		nil,
+	)
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 5, got.Limit)
+	assert.True(t, *got.IsCompleted)
+	assert.False(t, *got.Overdue)
+	assert.Equal(t, "go", got.Search)
+	assert.Equal(t, SortUpdatedAtDesc, got.Sort)
+	assert.Equal(t, cursor, *got.Cursor)
+}
+
+func TestGetAllTodosBadRequestInvalidQuery(t *testing.T) {
+	r := StubGetAll{func(q TodoQuery) (*TodoPage, error) {
+		return nil, nil
+	}}
+	for _, query := range []string{
+		"limit=0",
+		"limit=abc",
+		"isCompleted=maybe",
+		"overdue=soon",
+		"sort=task",
+		"cursor=asdf",
+	} {
+		h := GetAllTodos(r)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		h(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		got := MustUnmarshal[Problem](w.Body.Bytes())
+		assert.Len(t, got.Errors, 1, query)
+	}
+}
+
+func TestGetAllTodosBadRequestMismatchedCursor(t *testing.T) {
+	r := StubGetAll{func(q TodoQuery) (*TodoPage, error) {
+		return nil, ErrInvalidCursor
+	}}
+	h := GetAllTodos(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:invalid-query", got.Type)
+}
+
 type StubGetOneByID struct {
 	stub func(id uuid.UUID) (*Todo, error)
 }
 
-func (r StubGetOneByID) GetOneByID(id uuid.UUID) (*Todo, error) {
+func (r StubGetOneByID) GetOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
 	return r.stub(id)
 }
 
@@ -102,6 +173,8 @@ func TestGetOneTodoNotFoundInvalidIDParam(t *testing.T) {
 	c.Params = append(c.Params, gin.Param{Key: "id", Value: "asdf"})
 	h(c)
 	assert.Equal(t, http.StatusNotFound, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:invalid-id", got.Type)
 }
 
 func TestGetOneTodoNotFoundValidIDParam(t *testing.T) {
@@ -112,8 +185,11 @@ func TestGetOneTodoNotFoundValidIDParam(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
 	h(c)
 	assert.Equal(t, http.StatusNotFound, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:not-found", got.Type)
 }
 
 func TestGetOneTodoErrorValidIDParam(t *testing.T) {
@@ -124,8 +200,11 @@ func TestGetOneTodoErrorValidIDParam(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
 	h(c)
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Len(t, c.Errors, 1)
 }
 
 func TestGetOneTodoOkValidIDParam(t *testing.T) {
@@ -137,17 +216,55 @@ func TestGetOneTodoOkValidIDParam(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Params = append(c.Params, gin.Param{Key: "id", Value: want.ID})
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
 	h(c)
 	assert.Equal(t, http.StatusOK, w.Code)
 	got := MustUnmarshal[Todo](w.Body.Bytes())
 	assert.Equal(t, want, got)
 }
 
+type spyGetOneByID struct {
+	ctx context.Context
+}
+
+func (r *spyGetOneByID) GetOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	r.ctx = ctx
+	return nil, nil
+}
+
+func TestGetOneTodoPassesRequestContext(t *testing.T) {
+	type key struct{}
+	r := &spyGetOneByID{}
+	h := GetOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), key{}, "value"))
+	h(c)
+	assert.Equal(t, "value", r.ctx.Value(key{}))
+}
+
+func TestGetOneTodoSetsETag(t *testing.T) {
+	want := Todo{ID: uuid.NewString(), UpdatedAt: time.UnixMicro(1683900000123456)}
+	r := StubGetOneByID{func(id uuid.UUID) (*Todo, error) {
+		return &want, nil
+	}}
+	h := GetOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: want.ID})
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ETag(&want), w.Header().Get("ETag"))
+}
+
 type stubUpdateOneByID struct {
 	stub func(id uuid.UUID, todo Todo) (*Todo, error)
 }
 
-func (r stubUpdateOneByID) UpdateOneByID(id uuid.UUID, todo Todo) (*Todo, error) {
+func (r stubUpdateOneByID) UpdateOneByID(ctx context.Context, id uuid.UUID, todo Todo, ifMatch *time.Time) (*Todo, error) {
 	return r.stub(id, todo)
 }
 
@@ -243,11 +360,94 @@ func TestUpdateOneTodoOk(t *testing.T) {
 	assert.Equal(t, want, got)
 }
 
+type spyUpdateOneByID struct {
+	ifMatch *time.Time
+	err     error
+}
+
+func (r *spyUpdateOneByID) UpdateOneByID(ctx context.Context, id uuid.UUID, todo Todo, ifMatch *time.Time) (*Todo, error) {
+	r.ifMatch = ifMatch
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &Todo{ID: id.String(), Task: todo.Task, UpdatedAt: time.Now()}, nil
+}
+
+func TestUpdateOneTodoPassesIfMatch(t *testing.T) {
+	version := Todo{UpdatedAt: time.UnixMicro(1683900000123456)}
+	r := &spyUpdateOneByID{}
+	h := UpdateOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(`{"task":"Accept Go"}`))
+	c.Request.Header.Set("If-Match", ETag(&version))
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, version.UpdatedAt.Equal(*r.ifMatch))
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+}
+
+func TestUpdateOneTodoPreconditionFailed(t *testing.T) {
+	for _, header := range []string{`W/"abc"`, `"not-base-36!"`, "abc"} {
+		r := &spyUpdateOneByID{}
+		h := UpdateOneTodoByID(r)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+		c.Request = httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(`{"task":"Accept Go"}`))
+		c.Request.Header.Set("If-Match", header)
+		h(c)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, header)
+	}
+	r := &spyUpdateOneByID{err: ErrPreconditionFailed}
+	h := UpdateOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(`{"task":"Accept Go"}`))
+	c.Request.Header.Set("If-Match", `"1"`)
+	h(c)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:precondition-failed", got.Type)
+}
+
+func TestUpdateOneTodoParentChanged(t *testing.T) {
+	r := &spyUpdateOneByID{err: ErrParentChanged}
+	h := UpdateOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	body := `{"task":"Accept Go","parentTodoId":"` + uuid.NewString() + `"}`
+	c.Request = httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:parent-changed", got.Type)
+	assert.Equal(t, "parentTodoId", got.Errors[0].Field)
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	app := gin.New()
+	app.PUT("/", RequireIfMatch(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", nil))
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	req.Header.Set("If-Match", "*")
+	app.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 type StubCreateOne struct {
 	stub func(todo Todo) (*Todo, error)
 }
 
-func (r StubCreateOne) CreateOne(todo Todo) (*Todo, error) {
+func (r StubCreateOne) CreateOne(ctx context.Context, todo Todo) (*Todo, error) {
 	return r.stub(todo)
 }
 
@@ -260,6 +460,24 @@ func TestCreateOneTodoNoBody(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	h(c)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:malformed-json", got.Type)
+}
+
+func TestCreateOneTodoBodyTooLarge(t *testing.T) {
+	r := StubCreateOne{func(todo Todo) (*Todo, error) {
+		t.Fatal("should not be called")
+		return nil, nil
+	}}
+	h := CreateOneTodo(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"task":"` + strings.Repeat("a", MaxBodyBytes) + `"}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:body-too-large", got.Type)
 }
 
 func TestCreateOneTodoInvalid(t *testing.T) {
@@ -272,6 +490,58 @@ func TestCreateOneTodoInvalid(t *testing.T) {
 	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("{}"))
 	h(c)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "urn:todo-app:problem:validation-failed", got.Type)
+	assert.Equal(t, validation.Errors{{Field: "task", Detail: "must not be blank"}}, got.Errors)
+}
+
+func TestCreateOneTodoRejectsUnknownAndReadOnlyFields(t *testing.T) {
+	r := StubCreateOne{func(todo Todo) (*Todo, error) {
+		return nil, nil
+	}}
+	h := CreateOneTodo(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"task":"Learn Go","todoId":"x","createdAt":"2023-05-01T00:00:00Z","colour":1}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, validation.Errors{
+		{Field: "colour", Detail: "is not a recognized field"},
+		{Field: "createdAt", Detail: "is read-only"},
+		{Field: "todoId", Detail: "is read-only"},
+	}, got.Errors)
+}
+
+func TestCreateOneTodoRejectsLongTask(t *testing.T) {
+	r := StubCreateOne{func(todo Todo) (*Todo, error) {
+		return nil, nil
+	}}
+	h := CreateOneTodo(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"task":"` + strings.Repeat("a", MaxTaskLength+1) + `"}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "task", got.Errors[0].Field)
+}
+
+func TestCreateOneTodoRejectsUnknownPriority(t *testing.T) {
+	r := StubCreateOne{func(todo Todo) (*Todo, error) {
+		return nil, nil
+	}}
+	h := CreateOneTodo(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"task":"Learn Go","priority":"whenever"}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "priority", got.Errors[0].Field)
 }
 
 func TestCreateOneTodoError(t *testing.T) {
@@ -300,3 +570,480 @@ func TestCreateOneTodoOk(t *testing.T) {
 	got := MustUnmarshal[Todo](w.Body.Bytes())
 	assert.Equal(t, want, got)
 }
+
+type stubPatchOneByID struct {
+	stub func(id uuid.UUID, patch TodoPatch) (*Todo, error)
+}
+
+func (r stubPatchOneByID) PatchOneByID(ctx context.Context, id uuid.UUID, patch TodoPatch, ifMatch *time.Time) (*Todo, error) {
+	return r.stub(id, patch)
+}
+
+func TestPatchOneTodoNotFoundInvalidIDParam(t *testing.T) {
+	r := stubPatchOneByID{func(id uuid.UUID, patch TodoPatch) (*Todo, error) {
+		return nil, nil
+	}}
+	h := PatchOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "asdf"})
+	h(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPatchOneTodoBadRequestNoBody(t *testing.T) {
+	r := stubPatchOneByID{func(id uuid.UUID, patch TodoPatch) (*Todo, error) {
+		return nil, nil
+	}}
+	h := PatchOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPatchOneTodoBadRequestBlankTask(t *testing.T) {
+	r := stubPatchOneByID{func(id uuid.UUID, patch TodoPatch) (*Todo, error) {
+		return nil, nil
+	}}
+	h := PatchOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"task":"  "}`))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPatchOneTodoBadRequestWrongType(t *testing.T) {
+	r := stubPatchOneByID{func(id uuid.UUID, patch TodoPatch) (*Todo, error) {
+		return nil, nil
+	}}
+	h := PatchOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"isCompleted":"yes"}`))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, validation.Errors{{Field: "isCompleted", Detail: "must be a boolean"}}, got.Errors)
+}
+
+func TestPatchOneTodoNotFound(t *testing.T) {
+	r := stubPatchOneByID{func(id uuid.UUID, patch TodoPatch) (*Todo, error) {
+		return nil, nil
+	}}
+	h := PatchOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"isCompleted":true}`))
+	h(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPatchOneTodoError(t *testing.T) {
+	r := stubPatchOneByID{func(id uuid.UUID, patch TodoPatch) (*Todo, error) {
+		return nil, errors.New("oops!")
+	}}
+	h := PatchOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"isCompleted":true}`))
+	h(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestPatchOneTodoOnlySuppliedFields(t *testing.T) {
+	want := Todo{ID: uuid.NewString(), Task: "Learn Go", IsCompleted: true}
+	var got TodoPatch
+	r := stubPatchOneByID{func(id uuid.UUID, patch TodoPatch) (*Todo, error) {
+		got = patch
+		return &want, nil
+	}}
+	h := PatchOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: want.ID})
+	c.Request = httptest.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"isCompleted":true}`))
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, got.Task)
+	assert.True(t, *got.IsCompleted)
+	assert.False(t, got.DueAt.Set)
+	assert.Equal(t, want, MustUnmarshal[Todo](w.Body.Bytes()))
+}
+
+func TestPatchOneTodoClearsDueAt(t *testing.T) {
+	want := Todo{ID: uuid.NewString(), Task: "Learn Go", Priority: PriorityHigh}
+	var got TodoPatch
+	r := stubPatchOneByID{func(id uuid.UUID, patch TodoPatch) (*Todo, error) {
+		got = patch
+		return &want, nil
+	}}
+	h := PatchOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: want.ID})
+	body := `{"dueAt":null,"priority":"high"}`
+	c.Request = httptest.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(body))
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, got.DueAt.Set)
+	assert.Nil(t, got.DueAt.Value)
+	assert.Equal(t, PriorityHigh, *got.Priority)
+	assert.Equal(t, want, MustUnmarshal[Todo](w.Body.Bytes()))
+}
+
+type stubDeleteOneByID struct {
+	stub func(id uuid.UUID) (*Todo, error)
+}
+
+func (r stubDeleteOneByID) DeleteOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	return r.stub(id)
+}
+
+func TestDeleteOneTodoNotFoundInvalidIDParam(t *testing.T) {
+	r := stubDeleteOneByID{func(id uuid.UUID) (*Todo, error) {
+		return nil, nil
+	}}
+	h := DeleteOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "asdf"})
+	h(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteOneTodoNotFound(t *testing.T) {
+	r := stubDeleteOneByID{func(id uuid.UUID) (*Todo, error) {
+		return nil, nil
+	}}
+	h := DeleteOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodDelete, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteOneTodoError(t *testing.T) {
+	r := stubDeleteOneByID{func(id uuid.UUID) (*Todo, error) {
+		return nil, errors.New("oops!")
+	}}
+	h := DeleteOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodDelete, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestDeleteOneTodoNoContent(t *testing.T) {
+	id := uuid.New()
+	r := stubDeleteOneByID{func(id uuid.UUID) (*Todo, error) {
+		return &Todo{ID: id.String()}, nil
+	}}
+	h := DeleteOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: id.String()})
+	c.Request = httptest.NewRequest(http.MethodDelete, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+type stubRestoreOneByID struct {
+	stub func(id uuid.UUID) (*Todo, error)
+}
+
+func (r stubRestoreOneByID) RestoreOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	return r.stub(id)
+}
+
+func TestRestoreOneTodoNotFound(t *testing.T) {
+	r := stubRestoreOneByID{func(id uuid.UUID) (*Todo, error) {
+		return nil, nil
+	}}
+	h := RestoreOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRestoreOneTodoError(t *testing.T) {
+	r := stubRestoreOneByID{func(id uuid.UUID) (*Todo, error) {
+		return nil, errors.New("oops!")
+	}}
+	h := RestoreOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestRestoreOneTodoOk(t *testing.T) {
+	want := Todo{ID: uuid.NewString(), Task: "Learn Go"}
+	r := stubRestoreOneByID{func(id uuid.UUID) (*Todo, error) {
+		return &want, nil
+	}}
+	h := RestoreOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: want.ID})
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	got := MustUnmarshal[Todo](w.Body.Bytes())
+	assert.Equal(t, want, got)
+}
+
+type stubMoveOneByID struct {
+	stub func(id uuid.UUID, m TodoMove) (*Todo, error)
+}
+
+func (r stubMoveOneByID) MoveOneByID(ctx context.Context, id uuid.UUID, m TodoMove) (*Todo, error) {
+	return r.stub(id, m)
+}
+
+func TestMoveOneTodoBadRequestAfterAndBefore(t *testing.T) {
+	r := stubMoveOneByID{func(id uuid.UUID, m TodoMove) (*Todo, error) {
+		t.Fatal("should not be called")
+		return nil, nil
+	}}
+	h := MoveOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	body := fmt.Sprintf(`{"afterTodoId": %q, "beforeTodoId": %q}`, uuid.NewString(), uuid.NewString())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMoveOneTodoBadRequestSiblingNotFound(t *testing.T) {
+	r := stubMoveOneByID{func(id uuid.UUID, m TodoMove) (*Todo, error) {
+		return nil, ErrSiblingNotFound
+	}}
+	h := MoveOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	body := fmt.Sprintf(`{"beforeTodoId": %q}`, uuid.NewString())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "beforeTodoId", got.Errors[0].Field)
+}
+
+func TestMoveOneTodoNotFound(t *testing.T) {
+	r := stubMoveOneByID{func(id uuid.UUID, m TodoMove) (*Todo, error) {
+		return nil, nil
+	}}
+	h := MoveOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	h(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMoveOneTodoOk(t *testing.T) {
+	parentID := uuid.NewString()
+	want := Todo{ID: uuid.NewString(), Task: "Learn Go", ParentID: &parentID, Position: PositionGap}
+	r := stubMoveOneByID{func(id uuid.UUID, m TodoMove) (*Todo, error) {
+		assert.True(t, m.ParentID.Set)
+		assert.Equal(t, parentID, *m.ParentID.Value)
+		return &want, nil
+	}}
+	h := MoveOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: want.ID})
+	body := fmt.Sprintf(`{"parentTodoId": %q}`, parentID)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	got := MustUnmarshal[Todo](w.Body.Bytes())
+	assert.Equal(t, want, got)
+}
+
+type stubSearch struct {
+	stub func(q TodoSearch) ([]TodoSearchResult, error)
+}
+
+func (r stubSearch) Search(ctx context.Context, q TodoSearch) ([]TodoSearchResult, error) {
+	return r.stub(q)
+}
+
+func TestSearchTodosBadRequestBlankQuery(t *testing.T) {
+	r := stubSearch{func(q TodoSearch) ([]TodoSearchResult, error) {
+		t.Fatal("should not be called")
+		return nil, nil
+	}}
+	h := SearchTodos(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?q=%20&limit=0", nil)
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Len(t, got.Errors, 2)
+}
+
+func TestSearchTodosError(t *testing.T) {
+	r := stubSearch{func(q TodoSearch) ([]TodoSearchResult, error) {
+		return nil, errors.New("oops!")
+	}}
+	h := SearchTodos(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?q=go", nil)
+	h(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestSearchTodosOk(t *testing.T) {
+	want := TodoSearchPage{Results: []TodoSearchResult{{
+		Todo:    Todo{ID: uuid.NewString(), Task: "Learn Go"},
+		Rank:    0.1,
+		Snippet: "Learn <mark>Go</mark>",
+	}}}
+	r := stubSearch{func(q TodoSearch) ([]TodoSearchResult, error) {
+		assert.Equal(t, TodoSearch{Query: "learn -rust", Limit: 5}, q)
+		return want.Results, nil
+	}}
+	h := SearchTodos(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?q=learn+-rust&limit=5", nil)
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	got := MustUnmarshal[TodoSearchPage](w.Body.Bytes())
+	assert.Equal(t, want, got)
+}
+
+type stubGetHistory struct {
+	stub func(id uuid.UUID) ([]TodoEvent, error)
+}
+
+func (r stubGetHistory) GetHistory(ctx context.Context, id uuid.UUID) ([]TodoEvent, error) {
+	return r.stub(id)
+}
+
+func TestGetTodoHistoryNotFound(t *testing.T) {
+	r := stubGetHistory{func(id uuid.UUID) ([]TodoEvent, error) {
+		return []TodoEvent{}, nil
+	}}
+	h := GetTodoHistory(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetTodoHistoryOk(t *testing.T) {
+	todo := Todo{ID: uuid.NewString(), Task: "Learn Go"}
+	want := TodoHistory{Events: []TodoEvent{{
+		ID:      1,
+		TodoID:  todo.ID,
+		ActorID: uuid.NewString(),
+		Kind:    EventUpdated,
+		Before:  TodoChanges{"task": json.RawMessage(`"Accept Go"`)},
+		After:   TodoChanges{"task": json.RawMessage(`"Learn Go"`)},
+	}}}
+	r := stubGetHistory{func(id uuid.UUID) ([]TodoEvent, error) {
+		return want.Events, nil
+	}}
+	h := GetTodoHistory(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: todo.ID})
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	got := MustUnmarshal[TodoHistory](w.Body.Bytes())
+	assert.Equal(t, want, got)
+}
+
+type stubRevertOneByID struct {
+	stub func(id uuid.UUID, eventID int64) (*Todo, error)
+}
+
+func (r stubRevertOneByID) RevertOneByID(ctx context.Context, id uuid.UUID, eventID int64) (*Todo, error) {
+	return r.stub(id, eventID)
+}
+
+func TestRevertOneTodoBadRequestNoEventID(t *testing.T) {
+	r := stubRevertOneByID{func(id uuid.UUID, eventID int64) (*Todo, error) {
+		t.Fatal("should not be called")
+		return nil, nil
+	}}
+	h := RevertOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRevertOneTodoBadRequestEventNotFound(t *testing.T) {
+	r := stubRevertOneByID{func(id uuid.UUID, eventID int64) (*Todo, error) {
+		return nil, ErrEventNotFound
+	}}
+	h := RevertOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"eventId": 7}`))
+	h(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	got := MustUnmarshal[Problem](w.Body.Bytes())
+	assert.Equal(t, "eventId", got.Errors[0].Field)
+}
+
+func TestRevertOneTodoNotFound(t *testing.T) {
+	r := stubRevertOneByID{func(id uuid.UUID, eventID int64) (*Todo, error) {
+		return nil, nil
+	}}
+	h := RevertOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: uuid.NewString()})
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"eventId": 7}`))
+	h(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRevertOneTodoOk(t *testing.T) {
+	want := Todo{ID: uuid.NewString(), Task: "Learn Go"}
+	r := stubRevertOneByID{func(id uuid.UUID, eventID int64) (*Todo, error) {
+		assert.Equal(t, int64(7), eventID)
+		return &want, nil
+	}}
+	h := RevertOneTodoByID(r)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: want.ID})
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"eventId": 7}`))
+	h(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	got := MustUnmarshal[Todo](w.Body.Bytes())
+	assert.Equal(t, want, got)
+}
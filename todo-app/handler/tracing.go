@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Trace(tracer *tracing.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if sc, err := tracing.ParseTraceparent(c.GetHeader(tracing.TraceparentHeader)); err == nil {
+			ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route, tracing.SpanKindServer)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.status_code", c.Writer.Status())
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			span.RecordError(errors.New(http.StatusText(c.Writer.Status())))
+		}
+	}
+}
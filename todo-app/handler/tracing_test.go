@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type spanRecorder struct {
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(ctx context.Context, spans []tracing.SpanData) error {
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func TestTrace(t *testing.T) {
+	r := &spanRecorder{}
+	tracer := tracing.NewTracer(r)
+	var sc tracing.SpanContext
+	app := gin.New()
+	app.Use(Trace(tracer))
+	app.GET("/todos/:id", func(c *gin.Context) {
+		sc = tracing.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusServiceUnavailable)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/todos/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	tracer.Close()
+
+	assert.Len(t, r.spans, 1)
+	span := r.spans[0]
+	assert.Equal(t, "GET /todos/:id", span.Name)
+	assert.Equal(t, tracing.SpanKindServer, span.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", span.ParentID.String())
+	assert.Equal(t, sc.SpanID, span.SpanID)
+	assert.Equal(t, http.StatusServiceUnavailable, span.Attributes["http.status_code"])
+	assert.Equal(t, "Service Unavailable", span.Error)
+}
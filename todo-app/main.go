@@ -1,47 +1,339 @@
 package main
 
 import (
+	"config"
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
-	"todo-app/data"
+	"os/signal"
+	"syscall"
+	"time"
+	"todo-app/auth"
+	. "todo-app/data"
 	"todo-app/handler"
+	"todo-app/metrics"
+	"todo-app/openapi"
+	"todo-app/ratelimit"
 	. "todo-app/repository"
+	"tracing"
+	"write-to-stderr/logging"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
 
-func CreateApp(db data.DB) *gin.Engine {
+type Config struct {
+	RequireIfMatch bool          `env:"REQUIRE_IF_MATCH"`
+	TokenSecret    []byte        `env:"TOKEN_SECRET" required:"true"`
+	TokenTTL       time.Duration `env:"TOKEN_TTL" default:"24h"`
+	RateLimit      float64       `env:"RATE_LIMIT" usage:"requests per second per client, 0 disables rate limiting"`
+	RateLimitBurst int           `env:"RATE_LIMIT_BURST"`
+	TrustedProxies []string      `env:"TRUSTED_PROXIES" usage:"comma-separated proxy IPs or CIDRs whose X-Forwarded-For is trusted, none by default"`
+	Metrics        *metrics.Metrics
+	Tracer         *tracing.Tracer
+	Logger         *logging.Logger
+}
+
+const minTokenSecret = 32
+
+func (c Config) Validate() error {
+	var errs config.Errors
+	if len(c.TokenSecret) > 0 && len(c.TokenSecret) < minTokenSecret {
+		errs = append(errs, fmt.Errorf("TOKEN_SECRET must be at least %d bytes", minTokenSecret))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP or CIDR", proxy))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type StoreConfig struct {
+	Store        string        `env:"TODO_STORE" default:"postgres" usage:"memory or postgres"`
+	DatabaseURL  string        `env:"DATABASE_URL"`
+	SoftDelete   bool          `env:"SOFT_DELETE"`
+	QueryTimeout time.Duration `env:"QUERY_TIMEOUT"`
+	AutoMigrate  bool          `env:"AUTO_MIGRATE"`
+}
+
+func (c StoreConfig) Validate() error {
+	var errs config.Errors
+	switch c.Store {
+	case "memory":
+	case "postgres":
+		if c.DatabaseURL == "" {
+			errs = append(errs, errors.New("DATABASE_URL is required when TODO_STORE is postgres"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("TODO_STORE must be memory or postgres, not %q", c.Store))
+	}
+	if c.QueryTimeout < 0 {
+		errs = append(errs, errors.New("QUERY_TIMEOUT must not be negative"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type Settings struct {
+	Logging logging.Config
+	App     Config
+	Stores  StoreConfig
+	Server  ServerConfig
+	Tracing tracing.Config
+}
+
+type Stores struct {
+	DB      DB
+	Todos   TodoStore
+	Users   UserStore
+	APIKeys APIKeyStore
+	Lists   ListStore
+	Tags    TagStore
+}
+
+func CreateApp(stores Stores, config Config) *gin.Engine {
 	app := gin.New()
 
-	repo := NewTodoRepository(db)
+	if err := app.SetTrustedProxies(config.TrustedProxies); err != nil {
+		panic(err)
+	}
+
+	app.Use(handler.RequestID())
+
+	if config.Tracer != nil {
+		app.Use(handler.Trace(config.Tracer))
+	}
+
+	if config.Logger != nil {
+		app.Use(handler.RequestLogger(config.Logger))
+	}
+
+	if config.Metrics != nil {
+		app.Use(config.Metrics.Middleware())
+		app.GET("/metrics", config.Metrics.Handler())
+	}
+
+	app.Use(handler.Recover(), handler.Problems())
+	app.NoRoute(handler.NoRoute())
+
+	app.GET("/openapi.json", openapi.Handler)
+
+	checks := map[string]handler.Check{}
+
+	if stores.DB != nil {
+		checks["postgres"] = func(ctx context.Context) error {
+			return Ping(ctx, stores.DB)
+		}
+	}
+
+	app.GET("/healthz", handler.Healthz())
+	app.GET("/readyz", handler.Readyz(checks))
+
+	tokens := auth.NewTokens(config.TokenSecret, config.TokenTTL)
+	authenticate := handler.Authenticate(tokens, stores.APIKeys)
+
+	ipLimit := func(c *gin.Context) {}
+	failureLimit := func(c *gin.Context) {}
+	rateLimit := func(c *gin.Context) {}
+
+	// failureLimit runs before authenticate so that rejected credentials,
+	// each of which hits the store, are limited by address too.
+	if config.RateLimit > 0 {
+		ipLimit = handler.RateLimitByIP(ratelimit.NewLimiter(config.RateLimit, config.RateLimitBurst))
+		failureLimit = handler.RateLimitFailures(ratelimit.NewLimiter(config.RateLimit, config.RateLimitBurst))
+		rateLimit = handler.RateLimit(ratelimit.NewLimiter(config.RateLimit, config.RateLimitBurst))
+	}
+
+	read := handler.RequireScope(ScopeTodosRead)
+	write := handler.RequireScope(ScopeTodosWrite)
+
+	app.Group("/v1/auth", ipLimit).
+		POST("/signup", handler.Signup(stores.Users, tokens)).
+		POST("/login", handler.Login(stores.Users, tokens))
+
+	app.Group("/v1/api-keys", failureLimit, authenticate, rateLimit, handler.RequireScope(ScopeAPIKeys)).
+		GET("", handler.GetAPIKeys(stores.APIKeys)).
+		POST("", handler.CreateAPIKey(stores.APIKeys)).
+		DELETE("/:id", handler.DeleteAPIKeyByID(stores.APIKeys))
+
+	ifMatch := func(c *gin.Context) {}
+
+	if config.RequireIfMatch {
+		ifMatch = handler.RequireIfMatch()
+	}
+
+	repo := stores.Todos
+
+	if config.Tracer != nil {
+		repo = NewTracedTodoStore(repo, config.Tracer)
+	}
+
+	if config.Metrics != nil {
+		repo = config.Metrics.TodoStore(repo)
+	}
 
-	app.Group("/v1/todos").
-		GET("", handler.GetAllTodos(repo)).
-		GET("/:id", handler.GetOneTodoByID(repo)).
-		POST("", handler.CreateOneTodo(repo)).
-		PUT("/:id", handler.UpdateOneTodoByID(repo))
+	app.Group("/v1/todos", failureLimit, authenticate, rateLimit).
+		GET("", read, handler.GetAllTodos(repo)).
+		GET("/search", read, handler.SearchTodos(repo)).
+		GET("/:id", read, handler.GetOneTodoByID(repo)).
+		POST("", write, handler.CreateOneTodo(repo)).
+		PUT("/:id", write, ifMatch, handler.UpdateOneTodoByID(repo)).
+		PATCH("/:id", write, ifMatch, handler.PatchOneTodoByID(repo)).
+		DELETE("/:id", write, handler.DeleteOneTodoByID(repo)).
+		POST("/:id/restore", write, handler.RestoreOneTodoByID(repo)).
+		POST("/:id/move", write, handler.MoveOneTodoByID(repo)).
+		GET("/:id/subtasks", read, handler.GetSubtasks(repo, repo)).
+		GET("/:id/history", read, handler.GetTodoHistory(repo)).
+		POST("/:id/revert", write, handler.RevertOneTodoByID(repo))
+
+	// gin reads ":batch" as a parameter covering the rest of the segment, so
+	// this matches any POST /v1/todos<suffix> without a slash and CustomMethod
+	// lets only the literal ":batch" through. It cannot shadow /v1/todos/:id,
+	// whose path continues with a slash after "todos".
+	app.POST("/v1/todos:batch", failureLimit, authenticate, rateLimit, write, handler.CustomMethod("batch", handler.BatchTodos(repo)))
+
+	app.Group("/v1/lists", failureLimit, authenticate, rateLimit).
+		GET("", read, handler.GetLists(stores.Lists)).
+		GET("/:id", read, handler.GetListByID(stores.Lists)).
+		POST("", write, handler.CreateList(stores.Lists)).
+		PUT("/:id", write, handler.UpdateListByID(stores.Lists)).
+		DELETE("/:id", write, handler.DeleteListByID(stores.Lists)).
+		GET("/:id/todos", read, handler.GetListTodos(stores.Lists, repo)).
+		POST("/:id/todos", write, handler.CreateListTodo(stores.Lists, repo))
+
+	app.GET("/v1/tags", failureLimit, authenticate, rateLimit, read, handler.GetTags(stores.Tags))
 
 	return app
 }
 
+func createStores(c StoreConfig) (Stores, error) {
+	var opts []Option
+	if c.SoftDelete {
+		opts = append(opts, WithSoftDelete())
+	}
+	if c.QueryTimeout > 0 {
+		opts = append(opts, WithQueryTimeout(c.QueryTimeout))
+	}
+	if c.Store == "memory" {
+		todos := NewMemoryTodoRepository(opts...)
+		return Stores{
+			Todos:   todos,
+			Users:   NewMemoryUserRepository(),
+			APIKeys: NewMemoryAPIKeyRepository(),
+			Lists:   NewMemoryListRepository(todos),
+			Tags:    NewMemoryTagRepository(todos),
+		}, nil
+	}
+	db, err := sql.Open("postgres", c.DatabaseURL)
+	if err != nil {
+		return Stores{}, err
+	}
+	if c.AutoMigrate {
+		if err := runMigrate(context.Background(), db, []string{"up"}, os.Stdout); err != nil {
+			return Stores{}, fmt.Errorf("migrating: %w", err)
+		}
+	}
+	return Stores{
+		DB:      db,
+		Todos:   NewTodoRepository(db, opts...),
+		Users:   NewUserRepository(db, opts...),
+		APIKeys: NewAPIKeyRepository(db, opts...),
+		Lists:   NewListRepository(db, opts...),
+		Tags:    NewTagRepository(db, opts...),
+	}, nil
+}
+
+func exit(err error) {
+	logging.Default.Error("todo-app exited", "err", err)
+	os.Exit(1)
+}
+
 func main() {
-	db, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		var c struct {
+			DatabaseURL string `env:"DATABASE_URL" required:"true"`
+		}
+		if err := config.Load(&c, config.WithArgs(nil)); err != nil {
+			exit(err)
+		}
+		db, err := sql.Open("postgres", c.DatabaseURL)
+		if err == nil {
+			err = runMigrate(context.Background(), db, os.Args[2:], os.Stdout)
+			db.Close()
+		}
+		if err != nil {
+			exit(fmt.Errorf("migrate: %w", err))
+		}
+		return
+	}
+
+	var settings Settings
+
+	if err := config.Load(&settings); err != nil {
+		exit(err)
+	}
+
+	logging.Default = settings.Logging.NewLogger()
+
+	stores, err := createStores(settings.Stores)
 
 	if err != nil {
-		panic(err)
+		exit(err)
 	}
 
-	app := CreateApp(db)
+	settings.App.Metrics = metrics.New()
 
-	app.Use(
-		gin.LoggerWithWriter(gin.DefaultWriter),
-		gin.Recovery(),
-	)
+	if db, ok := stores.DB.(*sql.DB); ok {
+		if err := settings.App.Metrics.RegisterDB(db, "postgres"); err != nil {
+			exit(err)
+		}
+	}
+
+	if settings.App.Tracer, err = settings.Tracing.NewTracer("todo-app"); err != nil {
+		exit(err)
+	}
+
+	settings.App.Logger = logging.Default
 
 	gin.SetMode(gin.ReleaseMode)
 
-	if err := app.Run(os.Getenv("LISTEN_ADDRESS")); err != nil {
-		panic(err)
+	app := CreateApp(stores, settings.App)
+
+	listener, err := net.Listen("tcp", settings.Server.ListenAddress)
+
+	if err != nil {
+		exit(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	closers := []io.Closer{settings.App.Tracer}
+
+	if db, ok := stores.DB.(io.Closer); ok {
+		closers = append(closers, db)
 	}
+
+	logging.Default.Info("listening", "address", listener.Addr().String(), "store", settings.Stores.Store)
+
+	err = serve(ctx, newServer(app, settings.Server), listener, settings.Server.ShutdownTimeout, closers...)
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		exit(err)
+	}
+
+	logging.Default.Info("shut down")
 }
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	. "todo-app/data"
+	. "todo-app/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	app := gin.New()
+	app.GET("/metrics", m.Handler())
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	m := New()
+	app := gin.New()
+	app.Use(m.Middleware())
+	app.GET("/todos/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	for _, path := range []string{"/todos/1", "/todos/2", "/missing"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	body := scrape(t, m)
+	assert.Contains(t, body, `todo_app_http_requests_total{method="GET",route="/todos/:id",status="204"} 2`)
+	assert.Contains(t, body, `todo_app_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `todo_app_http_request_duration_seconds_count{method="GET",route="/todos/:id"} 2`)
+}
+
+func TestTodoStore(t *testing.T) {
+	m := New()
+	store := m.TodoStore(NewMemoryTodoRepository())
+	ctx := ContextWithUserID(context.Background(), uuid.New())
+	_, err := store.CreateOne(ctx, Todo{Task: "measure"})
+	assert.Nil(t, err)
+	_, err = store.GetAll(context.Background(), TodoQuery{})
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = store.GetOneByID(canceled, uuid.New())
+	assert.ErrorIs(t, err, context.Canceled)
+	body := scrape(t, m)
+	assert.Contains(t, body, `todo_app_repository_duration_seconds_count{method="CreateOne",outcome="ok"} 1`)
+	assert.Contains(t, body, `todo_app_repository_duration_seconds_count{method="GetAll",outcome="error"} 1`)
+	assert.Contains(t, body, `todo_app_repository_duration_seconds_count{method="GetOneByID",outcome="canceled"} 1`)
+}
+
+func TestRegisterDB(t *testing.T) {
+	m := New()
+	db, err := sql.Open("postgres", "")
+	assert.Nil(t, err)
+	defer db.Close()
+	assert.Nil(t, m.RegisterDB(db, "postgres"))
+	assert.Contains(t, scrape(t, m), `go_sql_open_connections{db_name="postgres"} 0`)
+}
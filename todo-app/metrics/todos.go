@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"time"
+	. "todo-app/data"
+	. "todo-app/repository"
+
+	"github.com/google/uuid"
+)
+
+type todoStore struct {
+	store   TodoStore
+	metrics *Metrics
+}
+
+func (m *Metrics) TodoStore(store TodoStore) TodoStore {
+	return &todoStore{store, m}
+}
+
+func (s *todoStore) CreateOne(ctx context.Context, t Todo) (_ *Todo, err error) {
+	defer s.metrics.observeQuery("CreateOne", time.Now(), &err)
+	return s.store.CreateOne(ctx, t)
+}
+
+func (s *todoStore) UpdateOneByID(ctx context.Context, id uuid.UUID, t Todo, ifMatch *time.Time) (_ *Todo, err error) {
+	defer s.metrics.observeQuery("UpdateOneByID", time.Now(), &err)
+	return s.store.UpdateOneByID(ctx, id, t, ifMatch)
+}
+
+func (s *todoStore) PatchOneByID(ctx context.Context, id uuid.UUID, p TodoPatch, ifMatch *time.Time) (_ *Todo, err error) {
+	defer s.metrics.observeQuery("PatchOneByID", time.Now(), &err)
+	return s.store.PatchOneByID(ctx, id, p, ifMatch)
+}
+
+func (s *todoStore) DeleteOneByID(ctx context.Context, id uuid.UUID) (_ *Todo, err error) {
+	defer s.metrics.observeQuery("DeleteOneByID", time.Now(), &err)
+	return s.store.DeleteOneByID(ctx, id)
+}
+
+func (s *todoStore) RestoreOneByID(ctx context.Context, id uuid.UUID) (_ *Todo, err error) {
+	defer s.metrics.observeQuery("RestoreOneByID", time.Now(), &err)
+	return s.store.RestoreOneByID(ctx, id)
+}
+
+func (s *todoStore) MoveOneByID(ctx context.Context, id uuid.UUID, m TodoMove) (_ *Todo, err error) {
+	defer s.metrics.observeQuery("MoveOneByID", time.Now(), &err)
+	return s.store.MoveOneByID(ctx, id, m)
+}
+
+func (s *todoStore) GetOneByID(ctx context.Context, id uuid.UUID) (_ *Todo, err error) {
+	defer s.metrics.observeQuery("GetOneByID", time.Now(), &err)
+	return s.store.GetOneByID(ctx, id)
+}
+
+func (s *todoStore) GetAll(ctx context.Context, q TodoQuery) (_ *TodoPage, err error) {
+	defer s.metrics.observeQuery("GetAll", time.Now(), &err)
+	return s.store.GetAll(ctx, q)
+}
+
+func (s *todoStore) Search(ctx context.Context, q TodoSearch) (_ []TodoSearchResult, err error) {
+	defer s.metrics.observeQuery("Search", time.Now(), &err)
+	return s.store.Search(ctx, q)
+}
+
+func (s *todoStore) GetHistory(ctx context.Context, id uuid.UUID) (_ []TodoEvent, err error) {
+	defer s.metrics.observeQuery("GetHistory", time.Now(), &err)
+	return s.store.GetHistory(ctx, id)
+}
+
+func (s *todoStore) RevertOneByID(ctx context.Context, id uuid.UUID, eventID int64) (_ *Todo, err error) {
+	defer s.metrics.observeQuery("RevertOneByID", time.Now(), &err)
+	return s.store.RevertOneByID(ctx, id, eventID)
+}
+
+func (s *todoStore) Batch(ctx context.Context, ops []TodoOperation, atomic bool) (_ []TodoOperationResult, err error) {
+	defer s.metrics.observeQuery("Batch", time.Now(), &err)
+	return s.store.Batch(ctx, ops, atomic)
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"migrate"
+	"todo-app/migrations"
+)
+
+func runMigrate(ctx context.Context, db *sql.DB, args []string, w io.Writer) error {
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	return m.Run(ctx, args, w)
+}
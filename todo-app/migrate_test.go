@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"migrate"
+	"testing"
+	"todo-app/migrations"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	loaded, err := migrate.Load(migrations.FS)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, loaded)
+	for _, m := range loaded {
+		assert.NotEmpty(t, m.Down, m.Name)
+	}
+}
+
+func TestRunMigrateUsage(t *testing.T) {
+	var out bytes.Buffer
+	for _, args := range [][]string{{}, {"sideways"}, {"down", "0"}, {"down", "1", "2"}} {
+		err := runMigrate(context.Background(), nil, args, &out)
+		assert.ErrorIs(t, err, migrate.ErrUsage, args)
+	}
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,2 @@
+alter table "todos"
+  drop column "deletedAt";
@@ -0,0 +1,2 @@
+alter table "todos"
+  add column "deletedAt" timestamptz;
@@ -0,0 +1,3 @@
+alter table "todos" drop column "userId";
+
+drop table "users";
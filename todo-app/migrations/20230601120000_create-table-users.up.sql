@@ -0,0 +1,27 @@
+create table "users" (
+  "userId"       uuid        not null default gen_random_uuid() primary key,
+  "email"        text        not null,
+  "passwordHash" text        not null,
+  "createdAt"    timestamptz not null default now()
+);
+
+create unique index "users_email_key" on "users" (lower("email"));
+
+alter table "todos" add column "userId" uuid references "users" ("userId") on delete cascade;
+
+-- todos created before users existed are given to a bootstrap user so they
+-- stay visible. Its password hash matches no password: to claim the todos,
+-- register an account and run
+--   update "todos" set "userId" = '<your userId>'
+--    where "userId" = (select "userId" from "users" where "email" = 'bootstrap@localhost');
+-- or set the bootstrap user's email and bcrypt "passwordHash" directly.
+insert into "users" ("email", "passwordHash")
+select 'bootstrap@localhost', '!'
+ where exists (select 1 from "todos");
+
+update "todos"
+   set "userId" = (select "userId" from "users" where "email" = 'bootstrap@localhost');
+
+alter table "todos" alter column "userId" set not null;
+
+create index "todos_userId_idx" on "todos" ("userId");
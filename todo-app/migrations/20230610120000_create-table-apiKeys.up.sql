@@ -0,0 +1,11 @@
+create table "apiKeys" (
+  "apiKeyId"  uuid        not null default gen_random_uuid() primary key,
+  "userId"    uuid        not null references "users" ("userId") on delete cascade,
+  "name"      text        not null,
+  "prefix"    text        not null,
+  "keyHash"   text        not null unique,
+  "scopes"    text[]      not null,
+  "createdAt" timestamptz not null default now()
+);
+
+create index "apiKeys_userId_idx" on "apiKeys" ("userId");
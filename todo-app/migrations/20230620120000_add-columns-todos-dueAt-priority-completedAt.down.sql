@@ -0,0 +1,6 @@
+drop index "todos_userId_priority_idx";
+
+alter table "todos"
+  drop column "completedAt",
+  drop column "priority",
+  drop column "dueAt";
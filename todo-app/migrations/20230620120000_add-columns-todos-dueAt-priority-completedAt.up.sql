@@ -0,0 +1,10 @@
+alter table "todos"
+  add column "dueAt"       timestamptz,
+  add column "priority"    smallint    not null default 1 check ("priority" between 0 and 3),
+  add column "completedAt" timestamptz;
+
+update "todos"
+   set "completedAt" = "updatedAt"
+ where "isCompleted";
+
+create index "todos_userId_priority_idx" on "todos" ("userId", "priority", "todoId");
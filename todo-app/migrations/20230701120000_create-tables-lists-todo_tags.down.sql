@@ -0,0 +1,7 @@
+drop table "todo_tags";
+
+alter table "todos" drop column "listId";
+
+drop table "lists";
+
+alter table "todos" drop constraint "todos_pkey";
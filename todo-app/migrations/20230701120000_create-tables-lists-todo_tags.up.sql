@@ -0,0 +1,28 @@
+alter table "todos" add primary key ("todoId");
+
+create table "lists" (
+  "listId"    uuid        not null default gen_random_uuid() primary key,
+  "userId"    uuid        not null references "users" ("userId") on delete cascade,
+  "name"      text        not null,
+  "createdAt" timestamptz not null default now(),
+  "updatedAt" timestamptz not null default now(),
+  unique ("listId", "userId")
+);
+
+create index "lists_userId_idx" on "lists" ("userId");
+
+-- referencing the owner too keeps todos out of other users' lists
+alter table "todos"
+  add column "listId" uuid,
+  add constraint "todos_listId_fkey"
+      foreign key ("listId", "userId") references "lists" ("listId", "userId");
+
+create index "todos_listId_idx" on "todos" ("listId");
+
+create table "todo_tags" (
+  "todoId" uuid not null references "todos" ("todoId") on delete cascade,
+  "tag"    text not null,
+  primary key ("todoId", "tag")
+);
+
+create index "todo_tags_tag_idx" on "todo_tags" ("tag");
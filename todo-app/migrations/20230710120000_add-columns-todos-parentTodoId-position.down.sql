@@ -0,0 +1,15 @@
+drop trigger "todos_derive_parent_completion_update" on "todos";
+drop trigger "todos_derive_parent_completion_delete" on "todos";
+drop trigger "todos_derive_parent_completion_insert" on "todos";
+drop function "todos_derive_parent_completion"();
+
+drop trigger "todos_derive_completion" on "todos";
+drop function "todos_derive_completion"();
+
+drop index "todos_parentTodoId_position_idx";
+
+alter table "todos"
+  drop column "deriveCompletion",
+  drop column "position",
+  drop column "parentTodoId",
+  drop constraint "todos_todoId_userId_key";
@@ -0,0 +1,89 @@
+alter table "todos"
+  add unique ("todoId", "userId"),
+  add column "parentTodoId"     uuid,
+  add column "position"         double precision,
+  add column "deriveCompletion" boolean not null default false,
+  add constraint "todos_parentTodoId_fkey"
+      foreign key ("parentTodoId", "userId") references "todos" ("todoId", "userId") on delete cascade;
+
+update "todos"
+   set "position" = "ranked"."position"
+  from (
+    select "todoId",
+           row_number() over (partition by "userId" order by "createdAt", "todoId") * 1024 as "position"
+      from "todos"
+  ) as "ranked"
+ where "todos"."todoId" = "ranked"."todoId";
+
+alter table "todos" alter column "position" set not null;
+
+create index "todos_parentTodoId_position_idx" on "todos" ("parentTodoId", "position");
+
+create function "todos_derive_completion"() returns trigger as $$
+begin
+  if new."deriveCompletion" and exists (
+    select 1
+      from "todos"
+     where "parentTodoId" = new."todoId"
+       and "deletedAt"    is null
+  ) then
+    new."isCompleted" := not exists (
+      select 1
+        from "todos"
+       where "parentTodoId" = new."todoId"
+         and "deletedAt"    is null
+         and not "isCompleted"
+    );
+    if not new."isCompleted" then
+      new."completedAt" := null;
+    elsif new."completedAt" is null then
+      new."completedAt" := now();
+    end if;
+    if tg_op = 'UPDATE' and new."isCompleted" <> old."isCompleted" then
+      -- updatedAt is the ETag, and now() repeats within a transaction
+      new."updatedAt" := clock_timestamp();
+    end if;
+  end if;
+  return new;
+end
+$$ language plpgsql;
+
+create trigger "todos_derive_completion"
+  before insert or update on "todos"
+  for each row execute function "todos_derive_completion"();
+
+-- touching a parent re-runs todos_derive_completion on it, which in turn
+-- touches its own parent whenever its completion changes
+create function "todos_derive_parent_completion"() returns trigger as $$
+declare
+  "parents" uuid[];
+begin
+  if tg_op in ('UPDATE', 'DELETE') then
+    "parents" := array[old."parentTodoId"];
+  end if;
+  if tg_op in ('INSERT', 'UPDATE') then
+    "parents" := "parents" || new."parentTodoId";
+  end if;
+  update "todos"
+     set "deriveCompletion" = "deriveCompletion"
+   where "todoId" = any("parents")
+     and "deriveCompletion";
+  return null;
+end
+$$ language plpgsql;
+
+create trigger "todos_derive_parent_completion_insert"
+  after insert on "todos"
+  for each row execute function "todos_derive_parent_completion"();
+
+create trigger "todos_derive_parent_completion_delete"
+  after delete on "todos"
+  for each row execute function "todos_derive_parent_completion"();
+
+create trigger "todos_derive_parent_completion_update"
+  after update on "todos"
+  for each row
+  when (old."isCompleted"  is distinct from new."isCompleted"
+     or old."deletedAt"    is distinct from new."deletedAt"
+     or old."parentTodoId" is distinct from new."parentTodoId")
+  execute function "todos_derive_parent_completion"();
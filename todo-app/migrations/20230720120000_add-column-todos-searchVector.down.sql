@@ -0,0 +1,3 @@
+drop index "todos_searchVector_idx";
+
+alter table "todos" drop column "searchVector";
@@ -0,0 +1,5 @@
+alter table "todos"
+  add column "searchVector" tsvector
+      generated always as (to_tsvector('english', "task")) stored;
+
+create index "todos_searchVector_idx" on "todos" using gin ("searchVector");
@@ -0,0 +1,4 @@
+drop trigger "todo_events_append_only" on "todo_events";
+drop function "todo_events_append_only"();
+
+drop table "todo_events";
@@ -0,0 +1,26 @@
+create table "todo_events" (
+  "eventId"   bigint      generated always as identity primary key,
+  "todoId"    uuid        not null,
+  "actorId"   uuid        not null references "users" ("userId") on delete cascade,
+  "kind"      text        not null,
+  "before"    jsonb,
+  "after"     jsonb,
+  "createdAt" timestamptz not null default now()
+);
+
+create index "todo_events_todoId_eventId_idx" on "todo_events" ("todoId", "eventId");
+
+-- history outlives hard-deleted todos, so only removing the actor removes it;
+-- the cascade from "users" runs one trigger level down
+create function "todo_events_append_only"() returns trigger as $$
+begin
+  if pg_trigger_depth() = 1 then
+    raise exception 'todo_events is append-only';
+  end if;
+  return old;
+end
+$$ language plpgsql;
+
+create trigger "todo_events_append_only"
+  before update or delete on "todo_events"
+  for each row execute function "todo_events_append_only"();
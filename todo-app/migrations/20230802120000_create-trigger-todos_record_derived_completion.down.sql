@@ -0,0 +1,2 @@
+drop trigger "todos_record_derived_completion" on "todos";
+drop function "todos_record_derived_completion"();
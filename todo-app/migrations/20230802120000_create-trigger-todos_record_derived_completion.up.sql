@@ -0,0 +1,21 @@
+-- completion derived from subtasks changes parents from inside the derive
+-- triggers, out of the repository's sight, so those changes are recorded here
+create function "todos_record_derived_completion"() returns trigger as $$
+begin
+  if pg_trigger_depth() > 1 then
+    insert into "todo_events" ("todoId", "actorId", "kind", "before", "after")
+    values (new."todoId",
+            new."userId",
+            'updated',
+            jsonb_build_object('isCompleted', old."isCompleted", 'completedAt', old."completedAt"),
+            jsonb_build_object('isCompleted', new."isCompleted", 'completedAt', new."completedAt"));
+  end if;
+  return null;
+end
+$$ language plpgsql;
+
+create trigger "todos_record_derived_completion"
+  after update on "todos"
+  for each row
+  when (old."isCompleted" is distinct from new."isCompleted")
+  execute function "todos_record_derived_completion"();
@@ -0,0 +1,15 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+func Handler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", Spec)
+}
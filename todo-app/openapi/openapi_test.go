@@ -0,0 +1,77 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	. "todo-app/data"
+	"todo-app/handler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type document struct {
+	OpenAPI    string `json:"openapi"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func jsonNames(v any) []string {
+	t := reflect.TypeOf(v)
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func propertyNames(properties map[string]any) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestSpecIsValidJSON(t *testing.T) {
+	var doc document
+	assert.NoError(t, json.Unmarshal(Spec, &doc))
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+}
+
+func TestSchemasMatchTypes(t *testing.T) {
+	var doc document
+	assert.NoError(t, json.Unmarshal(Spec, &doc))
+	schemas := map[string]any{
+		"Todo":             Todo{},
+		"TodoPatch":        TodoPatch{},
+		"TodoMove":         TodoMove{},
+		"TodoSearchResult": TodoSearchResult{},
+		"TodoSearchPage":   TodoSearchPage{},
+		"TodoEvent":        TodoEvent{},
+		"TodoHistory":      TodoHistory{},
+		"TodoRevert":       TodoRevert{},
+		"TodoPage":         TodoPage{},
+		"TodoBatch":        TodoBatch{},
+		"TodoOperation":    TodoOperation{},
+		"Credentials":      Credentials{},
+		"User":             User{},
+		"Health":           handler.Health{},
+		"APIKey":           APIKey{},
+		"List":             List{},
+		"Tag":              Tag{},
+	}
+	for name, v := range schemas {
+		schema, ok := doc.Components.Schemas[name]
+		if assert.True(t, ok, name) {
+			assert.ElementsMatch(t, jsonNames(v), propertyNames(schema.Properties), name)
+		}
+	}
+}
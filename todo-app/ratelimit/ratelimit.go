@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type Limiter struct {
+	rate      float64
+	burst     int
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (l *Limiter) seconds(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+func (l *Limiter) Allow(key string) Decision {
+	return l.take(key, true)
+}
+
+// Peek reports what Allow would decide without spending a token.
+func (l *Limiter) Peek(key string) Decision {
+	return l.take(key, false)
+}
+
+func (l *Limiter) take(key string, spend bool) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		if spend {
+			l.buckets[key] = b
+		}
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	d := Decision{Limit: l.burst}
+	if b.tokens >= 1 {
+		if spend {
+			b.tokens--
+		}
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.seconds(1 - b.tokens)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = l.seconds(float64(l.burst) - b.tokens)
+	return d
+}
+
+// Buckets that have refilled completely are indistinguishable from new ones,
+// so they can be dropped to keep memory bounded by recently active keys.
+func (l *Limiter) sweep(now time.Time) {
+	full := l.seconds(float64(l.burst))
+	if now.Sub(l.lastSweep) < full {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter(rate float64, burst int) (*Limiter, *time.Time) {
+	clock := time.Unix(0, 0)
+	l := NewLimiter(rate, burst)
+	l.now = func() time.Time { return clock }
+	return l, &clock
+}
+
+func TestLimiterAllowsABurst(t *testing.T) {
+	l, _ := newTestLimiter(1, 3)
+	for want := 2; want >= 0; want-- {
+		d := l.Allow("a")
+		assert.True(t, d.Allowed)
+		assert.Equal(t, 3, d.Limit)
+		assert.Equal(t, want, d.Remaining)
+	}
+	d := l.Allow("a")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, time.Second, d.RetryAfter)
+	assert.Equal(t, 3*time.Second, d.Reset)
+}
+
+func TestLimiterRefills(t *testing.T) {
+	l, clock := newTestLimiter(2, 1)
+	assert.True(t, l.Allow("a").Allowed)
+	assert.False(t, l.Allow("a").Allowed)
+	*clock = clock.Add(500 * time.Millisecond)
+	assert.True(t, l.Allow("a").Allowed)
+}
+
+func TestLimiterKeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter(1, 1)
+	assert.True(t, l.Allow("a").Allowed)
+	assert.False(t, l.Allow("a").Allowed)
+	assert.True(t, l.Allow("b").Allowed)
+}
+
+func TestLimiterSweepsFullBuckets(t *testing.T) {
+	l, clock := newTestLimiter(1, 1)
+	l.Allow("a")
+	*clock = clock.Add(time.Minute)
+	l.Allow("b")
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestLimiterPeekSpendsNothing(t *testing.T) {
+	l, _ := newTestLimiter(1, 1)
+	assert.True(t, l.Peek("a").Allowed)
+	assert.True(t, l.Peek("a").Allowed)
+	assert.True(t, l.Allow("a").Allowed)
+	assert.False(t, l.Peek("a").Allowed)
+}
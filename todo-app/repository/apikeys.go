@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, k APIKey) (*APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	DeleteAPIKeyByID(ctx context.Context, id uuid.UUID) (*APIKey, error)
+}
+
+var (
+	_ APIKeyStore = (*APIKeyRepository)(nil)
+	_ APIKeyStore = (*MemoryAPIKeyRepository)(nil)
+)
+
+type APIKeyRepository struct {
+	db DB
+	options
+}
+
+func NewAPIKeyRepository(db DB, opts ...Option) *APIKeyRepository {
+	return &APIKeyRepository{db, newOptions(opts)}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row scanner) (*APIKey, error) {
+	var key APIKey
+	var scopes []string
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&scopes), &key.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning row: %w", err)
+	}
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, Scope(scope))
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, k APIKey) (*APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scopes := make([]string, len(k.Scopes))
+	for i, scope := range k.Scopes {
+		scopes[i] = string(scope)
+	}
+	return scanAPIKey(r.db.QueryRowContext(ctx, `--sql
+		insert into "apiKeys" ("userId", "name", "prefix", "keyHash", "scopes")
+		values ($1, $2, $3, $4, $5)
+		returning "apiKeyId",
+				  "userId",
+				  "name",
+				  "prefix",
+				  "keyHash",
+				  "scopes",
+				  "createdAt"
+	`, owner, k.Name, k.Prefix, k.KeyHash, pq.Array(scopes)))
+}
+
+func (r *APIKeyRepository) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `--sql
+		select "apiKeyId",
+			   "userId",
+			   "name",
+			   "prefix",
+			   "keyHash",
+			   "scopes",
+			   "createdAt"
+		  from "apiKeys"
+		 where "userId" = $1
+		 order by "createdAt", "apiKeyId"
+	`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("querying database: %w", err)
+	}
+	defer rows.Close()
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	return scanAPIKey(r.db.QueryRowContext(ctx, `--sql
+		select "apiKeyId",
+			   "userId",
+			   "name",
+			   "prefix",
+			   "keyHash",
+			   "scopes",
+			   "createdAt"
+		  from "apiKeys"
+		 where "keyHash" = $1
+	`, hash))
+}
+
+func (r *APIKeyRepository) DeleteAPIKeyByID(ctx context.Context, id uuid.UUID) (*APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return scanAPIKey(r.db.QueryRowContext(ctx, `--sql
+		delete from "apiKeys"
+		 where "apiKeyId" = $1
+		   and "userId"   = $2
+		returning "apiKeyId",
+				  "userId",
+				  "name",
+				  "prefix",
+				  "keyHash",
+				  "scopes",
+				  "createdAt"
+	`, id, owner))
+}
+
+type MemoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]*APIKey
+}
+
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{keys: make(map[uuid.UUID]*APIKey)}
+}
+
+func (r *MemoryAPIKeyRepository) CreateAPIKey(ctx context.Context, k APIKey) (*APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := uuid.New()
+	key := APIKey{
+		ID:        id.String(),
+		UserID:    owner.String(),
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		KeyHash:   k.KeyHash,
+		Scopes:    append([]Scope(nil), k.Scopes...),
+		CreatedAt: now(),
+	}
+	r.keys[id] = &key
+	created := key
+	return &created, nil
+}
+
+func (r *MemoryAPIKeyRepository) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]APIKey, 0)
+	for _, key := range r.keys {
+		if key.UserID == owner.String() {
+			keys = append(keys, *key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (r *MemoryAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, key := range r.keys {
+		if key.KeyHash == hash {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryAPIKeyRepository) DeleteAPIKeyByID(ctx context.Context, id uuid.UUID) (*APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok || key.UserID != owner.String() {
+		return nil, nil
+	}
+	delete(r.keys, id)
+	deleted := *key
+	return &deleted, nil
+}
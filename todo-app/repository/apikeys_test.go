@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"testing"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryAPIKeyStore(t *testing.T) {
+	testAPIKeyStore(t, func(t *testing.T) APIKeyStore {
+		return NewMemoryAPIKeyRepository()
+	})
+}
+
+func TestPostgresAPIKeyStore(t *testing.T) {
+	testAPIKeyStore(t, func(t *testing.T) APIKeyStore {
+		return NewAPIKeyRepository(beginTx(t))
+	})
+}
+
+func testAPIKeyStore(t *testing.T, newStore func(t *testing.T) APIKeyStore) {
+	t.Run("creates, finds and deletes a key", func(t *testing.T) {
+		s := newStore(t)
+		created, err := s.CreateAPIKey(ctx, APIKey{
+			Name:    "importer",
+			Prefix:  "todo_abc",
+			KeyHash: "hash",
+			Scopes:  []Scope{ScopeTodosRead, ScopeTodosWrite},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, testUserID.String(), created.UserID)
+		found, err := s.GetAPIKeyByHash(ctx, "hash")
+		assert.Nil(t, err)
+		assert.Equal(t, created.ID, found.ID)
+		assert.Equal(t, []Scope{ScopeTodosRead, ScopeTodosWrite}, found.Scopes)
+		keys, err := s.GetAPIKeys(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, keys, 1)
+		deleted, err := s.DeleteAPIKeyByID(ctx, uuid.MustParse(created.ID))
+		assert.Nil(t, err)
+		assert.Equal(t, created.ID, deleted.ID)
+		found, err = s.GetAPIKeyByHash(ctx, "hash")
+		assert.Nil(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("scopes keys to their owner", func(t *testing.T) {
+		s := newStore(t)
+		created, err := s.CreateAPIKey(ctx, APIKey{Name: "importer", KeyHash: "hash", Scopes: []Scope{ScopeTodosRead}})
+		assert.Nil(t, err)
+		keys, err := s.GetAPIKeys(otherCtx)
+		assert.Nil(t, err)
+		assert.Len(t, keys, 0)
+		deleted, err := s.DeleteAPIKeyByID(otherCtx, uuid.MustParse(created.ID))
+		assert.Nil(t, err)
+		assert.Nil(t, deleted)
+	})
+}
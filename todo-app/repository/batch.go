@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var errRollback = errors.New("rollback")
+
+func abortBatch(results []TodoOperationResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = TodoOperationResult{Err: ErrBatchAborted}
+		}
+	}
+}
+
+func (r *TodoRepository) Batch(ctx context.Context, ops []TodoOperation, atomic bool) ([]TodoOperationResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var results []TodoOperationResult
+	err = r.WithTx(ctx, func(tx *TodoRepository) error {
+		results = make([]TodoOperationResult, len(ops))
+		run := func(f func() error) error {
+			if atomic {
+				return f()
+			}
+			return tx.inSavepoint(ctx, "operation", f)
+		}
+		// operations run in request order; an atomic batch inserts runs of
+		// creates together, since one failure aborts them all anyway
+		for i := 0; i < len(ops); {
+			group := []int{i}
+			for atomic && ops[i].Op == OpCreate && i+len(group) < len(ops) && ops[i+len(group)].Op == OpCreate {
+				group = append(group, i+len(group))
+			}
+			err := run(func() error {
+				if ops[i].Op == OpCreate {
+					return tx.createMany(ctx, owner, ops, group, results)
+				}
+				var err error
+				results[i].Todo, err = tx.apply(ctx, ops[i])
+				return err
+			})
+			if err != nil {
+				for _, j := range group {
+					results[j] = TodoOperationResult{Err: err}
+				}
+				if atomic {
+					abortBatch(results)
+					return errRollback
+				}
+			}
+			i += len(group)
+		}
+		return nil
+	}, Serializable())
+	if err != nil && !errors.Is(err, errRollback) {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *TodoRepository) createMany(ctx context.Context, owner uuid.UUID, ops []TodoOperation, creates []int, results []TodoOperationResult) error {
+	ids := make([]string, len(creates))
+	values := make([]string, len(creates))
+	args := make([]any, 1, len(creates)*8+1)
+	args[0] = owner
+	var tagIDs, tags []string
+	appended := make(map[string]int)
+	for n, i := range creates {
+		ids[n] = uuid.NewString()
+		todo := ops[i].Todo
+		var parent string
+		if todo.ParentID != nil {
+			parent = *todo.ParentID
+		}
+		appended[parent]++
+		args = append(args, ids[n], todo.Task, todo.IsCompleted, todo.DueAt, todo.Priority, todo.ListID,
+			todo.ParentID, appended[parent]*PositionGap, todo.DeriveCompletion)
+		p := len(args) - 8
+		values[n] = fmt.Sprintf(`($%[1]d, $%[2]d, $%[3]d, $%[4]d, $%[5]d, case when $%[3]d then now() end, $%[6]d,
+				$%[7]d::uuid, coalesce((select max("position")
+										  from "todos"
+										 where "userId"       = $1
+										   and "parentTodoId" is not distinct from $%[7]d::uuid), 0) + $%[8]d,
+				$%[9]d, $1)`,
+			p, p+1, p+2, p+3, p+4, p+5, p+6, p+7, p+8,
+		)
+		for _, tag := range NormalizeTags(todo.Tags) {
+			tagIDs, tags = append(tagIDs, ids[n]), append(tags, tag)
+		}
+	}
+	var parents []string
+	for parent := range appended {
+		if parent != "" {
+			parents = append(parents, parent)
+		}
+	}
+	if len(parents) > 0 {
+		var found int
+		err := r.db.QueryRowContext(ctx, `--sql
+			select count(*)
+			  from "todos"
+			 where "todoId"    = any($1::uuid[])
+			   and "userId"    = $2
+			   and "deletedAt" is null
+		`, pq.Array(parents), owner).Scan(&found)
+		if err != nil {
+			return fmt.Errorf("checking parents: %w", err)
+		}
+		if found < len(parents) {
+			return ErrParentNotFound
+		}
+	}
+	rows, err := r.db.QueryContext(ctx, `--sql
+		insert into "todos" ("todoId", "task", "isCompleted", "dueAt", "priority", "completedAt", "listId",
+							 "parentTodoId", "position", "deriveCompletion", "userId")
+		values `+strings.Join(values, ",\n\t\t\t   ")+`
+		returning `+todoColumns+`, '{}'::text[]
+	`, args...)
+	var created []Todo
+	if err == nil {
+		created, err = scanTodos(rows)
+	}
+	if err := constraintError(err); err != nil {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("creating todos: %w", err)
+	}
+	if len(tags) > 0 {
+		_, err := r.db.ExecContext(ctx, `--sql
+			insert into "todo_tags" ("todoId", "tag")
+			select * from unnest($1::uuid[], $2::text[])
+		`, pq.Array(tagIDs), pq.Array(tags))
+		if err != nil {
+			return fmt.Errorf("inserting tags: %w", err)
+		}
+	}
+	byID := make(map[string]Todo, len(created))
+	for _, todo := range created {
+		byID[todo.ID] = todo
+	}
+	changes := make([]todoChange, len(creates))
+	for n, i := range creates {
+		todo := byID[ids[n]]
+		todo.Tags = NormalizeTags(ops[i].Todo.Tags)
+		results[i].Todo = &todo
+		changes[n] = todoChange{after: todo}
+	}
+	return r.record(ctx, owner, EventCreated, changes...)
+}
+
+func (r *TodoRepository) apply(ctx context.Context, op TodoOperation) (*Todo, error) {
+	id, err := uuid.Parse(op.ID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var todo *Todo
+	switch op.Op {
+	case OpUpdate:
+		todo, err = r.UpdateOneByID(ctx, id, *op.Todo, nil)
+	case OpDelete:
+		todo, err = r.DeleteOneByID(ctx, id)
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", op.Op)
+	}
+	if err == nil && todo == nil {
+		return nil, ErrNotFound
+	}
+	return todo, err
+}
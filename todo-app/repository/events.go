@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type todoChange struct {
+	before *Todo
+	after  Todo
+}
+
+// audited runs f in a transaction with the todo locked, and records what f
+// changed in the todo's history before committing.
+func (r *TodoRepository) audited(ctx context.Context, kind TodoEventKind, id *uuid.UUID, f func(tx *TodoRepository) (*Todo, error), opts ...TxOption) (*Todo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var todo *Todo
+	err = r.WithTx(ctx, func(tx *TodoRepository) error {
+		var before *Todo
+		if id != nil {
+			if before, err = tx.lockOne(ctx, *id, owner); err != nil {
+				return err
+			}
+		}
+		if todo, err = f(tx); err != nil || todo == nil {
+			return err
+		}
+		return tx.record(ctx, owner, kind, todoChange{before, *todo})
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+func (r *TodoRepository) lockOne(ctx context.Context, id uuid.UUID, owner uuid.UUID) (*Todo, error) {
+	return scanTodo(r.db.QueryRowContext(ctx, `--sql
+		select `+todoColumns+`, `+tagsOf(`"todos"`)+`
+		  from "todos"
+		 where "todoId" = $1
+		   and "userId" = $2
+		   for update
+	`, id, owner))
+}
+
+func nullJSON(changes TodoChanges) (sql.NullString, error) {
+	if changes == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("encoding changes: %w", err)
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func (r *TodoRepository) record(ctx context.Context, owner uuid.UUID, kind TodoEventKind, changes ...todoChange) error {
+	ids := make([]string, len(changes))
+	befores := make([]sql.NullString, len(changes))
+	afters := make([]sql.NullString, len(changes))
+	for i, change := range changes {
+		before, after, err := DiffTodos(change.before, change.after)
+		if err != nil {
+			return fmt.Errorf("diffing todos: %w", err)
+		}
+		ids[i] = change.after.ID
+		if befores[i], err = nullJSON(before); err != nil {
+			return err
+		}
+		if afters[i], err = nullJSON(after); err != nil {
+			return err
+		}
+	}
+	_, err := r.db.ExecContext(ctx, `--sql
+		insert into "todo_events" ("todoId", "actorId", "kind", "before", "after")
+		select "todoId", $1, $2, "before", "after"
+		  from unnest($3::uuid[], $4::jsonb[], $5::jsonb[]) as "changes" ("todoId", "before", "after")
+	`, owner, kind, pq.Array(ids), pq.Array(befores), pq.Array(afters))
+	if err != nil {
+		return fmt.Errorf("recording events: %w", err)
+	}
+	return nil
+}
+
+func (r *TodoRepository) history(ctx context.Context, id uuid.UUID, owner uuid.UUID, since int64) ([]TodoEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `--sql
+		select "eventId", "todoId", "actorId", "kind", "before", "after", "createdAt"
+		  from "todo_events"
+		 where "todoId"  =  $1
+		   and "actorId" =  $2
+		   and "eventId" >= $3
+		 order by "eventId"
+	`, id, owner, since)
+	if err != nil {
+		return nil, fmt.Errorf("querying database: %w", err)
+	}
+	defer rows.Close()
+	events := make([]TodoEvent, 0)
+	for rows.Next() {
+		var event TodoEvent
+		err := rows.Scan(&event.ID, &event.TodoID, &event.ActorID, &event.Kind, &event.Before, &event.After, &event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return events, nil
+}
+
+func (r *TodoRepository) GetHistory(ctx context.Context, id uuid.UUID) ([]TodoEvent, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.history(ctx, id, owner, 0)
+}
+
+func (r *TodoRepository) RevertOneByID(ctx context.Context, id uuid.UUID, eventID int64) (*Todo, error) {
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.audited(ctx, EventReverted, &id, func(tx *TodoRepository) (*Todo, error) {
+		current, err := tx.GetOneByID(ctx, id)
+		if err != nil || current == nil {
+			return nil, err
+		}
+		events, err := tx.history(ctx, id, owner, eventID)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) == 0 || events[0].ID != eventID {
+			return nil, ErrEventNotFound
+		}
+		reverted, err := RevertTodo(*current, events[1:])
+		if err != nil {
+			return nil, err
+		}
+		return tx.updateOneByID(ctx, id, reverted, nil)
+	})
+}
+
+func (r *MemoryTodoRepository) record(owner uuid.UUID, kind TodoEventKind, before *Todo, after Todo) error {
+	removed, added, err := DiffTodos(before, after)
+	if err != nil {
+		return fmt.Errorf("diffing todos: %w", err)
+	}
+	r.events = append(r.events, TodoEvent{
+		ID:        int64(len(r.events) + 1),
+		TodoID:    after.ID,
+		ActorID:   owner.String(),
+		Kind:      kind,
+		Before:    removed,
+		After:     added,
+		CreatedAt: now(),
+	})
+	return nil
+}
+
+func (r *MemoryTodoRepository) history(owner uuid.UUID, id uuid.UUID, since int64) []TodoEvent {
+	events := make([]TodoEvent, 0)
+	for _, event := range r.events {
+		if event.TodoID == id.String() && event.ActorID == owner.String() && event.ID >= since {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (r *MemoryTodoRepository) GetHistory(ctx context.Context, id uuid.UUID) ([]TodoEvent, error) {
+	var events []TodoEvent
+	err := r.read(ctx, func(owner uuid.UUID) {
+		events = r.history(owner, id, 0)
+	})
+	return events, err
+}
+
+func (r *MemoryTodoRepository) RevertOneByID(ctx context.Context, id uuid.UUID, eventID int64) (*Todo, error) {
+	return r.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		todo := r.find(owner, id)
+		if todo == nil {
+			return nil, nil
+		}
+		events := r.history(owner, id, eventID)
+		if len(events) == 0 || events[0].ID != eventID {
+			return nil, ErrEventNotFound
+		}
+		reverted, err := RevertTodo(todo.Todo, events[1:])
+		if err != nil {
+			return nil, err
+		}
+		return r.update(owner, todo, reverted, EventReverted)
+	})
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+)
+
+type ListStore interface {
+	CreateList(ctx context.Context, l List) (*List, error)
+	GetLists(ctx context.Context) ([]List, error)
+	GetListByID(ctx context.Context, id uuid.UUID) (*List, error)
+	UpdateListByID(ctx context.Context, id uuid.UUID, l List) (*List, error)
+	DeleteListByID(ctx context.Context, id uuid.UUID) (*List, error)
+}
+
+var (
+	_ ListStore = (*ListRepository)(nil)
+	_ ListStore = (*MemoryListRepository)(nil)
+)
+
+type ListRepository struct {
+	db DB
+	options
+}
+
+func NewListRepository(db DB, opts ...Option) *ListRepository {
+	return &ListRepository{db, newOptions(opts)}
+}
+
+func scanList(row scanner) (*List, error) {
+	var list List
+	err := row.Scan(&list.ID, &list.Name, &list.CreatedAt, &list.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning row: %w", err)
+	}
+	return &list, nil
+}
+
+func (r *ListRepository) CreateList(ctx context.Context, l List) (*List, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return scanList(r.db.QueryRowContext(ctx, `--sql
+		insert into "lists" ("name", "userId")
+		values ($1, $2)
+		returning "listId",
+				  "name",
+				  "createdAt",
+				  "updatedAt"
+	`, l.Name, owner))
+}
+
+func (r *ListRepository) GetLists(ctx context.Context) ([]List, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `--sql
+		select "listId",
+			   "name",
+			   "createdAt",
+			   "updatedAt"
+		  from "lists"
+		 where "userId" = $1
+		 order by "createdAt", "listId"
+	`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("querying database: %w", err)
+	}
+	defer rows.Close()
+	lists := make([]List, 0)
+	for rows.Next() {
+		list, err := scanList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, *list)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return lists, nil
+}
+
+func (r *ListRepository) GetListByID(ctx context.Context, id uuid.UUID) (*List, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return scanList(r.db.QueryRowContext(ctx, `--sql
+		select "listId",
+			   "name",
+			   "createdAt",
+			   "updatedAt"
+		  from "lists"
+		 where "listId" = $1
+		   and "userId" = $2
+	`, id, owner))
+}
+
+func (r *ListRepository) UpdateListByID(ctx context.Context, id uuid.UUID, l List) (*List, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return scanList(r.db.QueryRowContext(ctx, `--sql
+		update "lists"
+		   set "name"      = $1,
+			   "updatedAt" = now()
+		 where "listId"    = $2
+		   and "userId"    = $3
+		returning "listId",
+				  "name",
+				  "createdAt",
+				  "updatedAt"
+	`, l.Name, id, owner))
+}
+
+func (r *ListRepository) DeleteListByID(ctx context.Context, id uuid.UUID) (*List, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return scanList(r.db.QueryRowContext(ctx, `--sql
+		with "detached" as (
+			update "todos"
+			   set "listId"    = null,
+				   "updatedAt" = clock_timestamp()
+			 where "listId"    = $1
+			   and "userId"    = $2
+			returning "todoId"
+		), "recorded" as (
+			insert into "todo_events" ("todoId", "actorId", "kind", "before", "after")
+			select "todoId", $2, $3, jsonb_build_object('listId', $1::uuid), jsonb_build_object('listId', null)
+			  from "detached"
+		)
+		delete from "lists"
+		 where "listId" = $1
+		   and "userId" = $2
+		returning "listId",
+				  "name",
+				  "createdAt",
+				  "updatedAt"
+	`, id, owner, EventUpdated))
+}
+
+type memoryList struct {
+	List
+	owner uuid.UUID
+}
+
+type MemoryListRepository struct {
+	todos *MemoryTodoRepository
+}
+
+func NewMemoryListRepository(todos *MemoryTodoRepository) *MemoryListRepository {
+	return &MemoryListRepository{todos}
+}
+
+func (r *MemoryListRepository) CreateList(ctx context.Context, l List) (*List, error) {
+	var created *List
+	_, err := r.todos.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		id := uuid.New()
+		at := now()
+		list := List{ID: id.String(), Name: l.Name, CreatedAt: at, UpdatedAt: at}
+		r.todos.lists[id] = &memoryList{list, owner}
+		created = &list
+		return nil, nil
+	})
+	return created, err
+}
+
+func (r *MemoryListRepository) GetLists(ctx context.Context) ([]List, error) {
+	lists := make([]List, 0)
+	err := r.todos.read(ctx, func(owner uuid.UUID) {
+		for _, list := range r.todos.lists {
+			if list.owner == owner {
+				lists = append(lists, list.List)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		if !lists[i].CreatedAt.Equal(lists[j].CreatedAt) {
+			return lists[i].CreatedAt.Before(lists[j].CreatedAt)
+		}
+		return lists[i].ID < lists[j].ID
+	})
+	return lists, nil
+}
+
+func (r *MemoryListRepository) GetListByID(ctx context.Context, id uuid.UUID) (*List, error) {
+	var found *List
+	err := r.todos.read(ctx, func(owner uuid.UUID) {
+		if list := r.todos.findList(owner, id); list != nil {
+			copied := list.List
+			found = &copied
+		}
+	})
+	return found, err
+}
+
+func (r *MemoryListRepository) UpdateListByID(ctx context.Context, id uuid.UUID, l List) (*List, error) {
+	var updated *List
+	_, err := r.todos.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		list := r.todos.findList(owner, id)
+		if list == nil {
+			return nil, nil
+		}
+		list.Name = l.Name
+		list.UpdatedAt = touch(list.UpdatedAt)
+		copied := list.List
+		updated = &copied
+		return nil, nil
+	})
+	return updated, err
+}
+
+func (r *MemoryListRepository) DeleteListByID(ctx context.Context, id uuid.UUID) (*List, error) {
+	var deleted *List
+	_, err := r.todos.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		list := r.todos.findList(owner, id)
+		if list == nil {
+			return nil, nil
+		}
+		for _, todo := range r.todos.todos {
+			if todo.ListID != nil && *todo.ListID == list.ID {
+				before := todo.Todo
+				todo.ListID = nil
+				todo.UpdatedAt = touch(todo.UpdatedAt)
+				if err := r.todos.record(owner, EventUpdated, &before, todo.Todo); err != nil {
+					return nil, err
+				}
+			}
+		}
+		delete(r.todos.lists, id)
+		deleted = &list.List
+		return nil, nil
+	})
+	return deleted, err
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"testing"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type newListStores func(t *testing.T) (ListStore, TodoStore, TagStore)
+
+func TestMemoryListStore(t *testing.T) {
+	testListStore(t, func(t *testing.T) (ListStore, TodoStore, TagStore) {
+		todos := NewMemoryTodoRepository()
+		return NewMemoryListRepository(todos), todos, NewMemoryTagRepository(todos)
+	})
+}
+
+func TestPostgresListStore(t *testing.T) {
+	testListStore(t, func(t *testing.T) (ListStore, TodoStore, TagStore) {
+		tx := beginTx(t)
+		return NewListRepository(tx), NewTodoRepository(tx), NewTagRepository(tx)
+	})
+}
+
+func testListStore(t *testing.T, newStores newListStores) {
+	t.Run("creates, renames and deletes a list", func(t *testing.T) {
+		lists, _, _ := newStores(t)
+		created, err := lists.CreateList(ctx, List{Name: "Work"})
+		assert.Nil(t, err)
+		assert.Equal(t, "Work", created.Name)
+		id := uuid.MustParse(created.ID)
+		updated, err := lists.UpdateListByID(ctx, id, List{Name: "Office"})
+		assert.Nil(t, err)
+		assert.Equal(t, "Office", updated.Name)
+		all, err := lists.GetLists(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, all, 1)
+		deleted, err := lists.DeleteListByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, created.ID, deleted.ID)
+		found, err := lists.GetListByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("scopes lists to their owner", func(t *testing.T) {
+		lists, todos, _ := newStores(t)
+		created, _ := lists.CreateList(ctx, List{Name: "Work"})
+		all, err := lists.GetLists(otherCtx)
+		assert.Nil(t, err)
+		assert.Len(t, all, 0)
+		found, err := lists.GetListByID(otherCtx, uuid.MustParse(created.ID))
+		assert.Nil(t, err)
+		assert.Nil(t, found)
+		todo, err := todos.CreateOne(otherCtx, Todo{Task: "Borrow a list", ListID: &created.ID})
+		assert.ErrorIs(t, err, ErrListNotFound)
+		assert.Nil(t, todo)
+	})
+
+	t.Run("files todos in lists", func(t *testing.T) {
+		lists, todos, _ := newStores(t)
+		list, _ := lists.CreateList(ctx, List{Name: "Work"})
+		listID := uuid.MustParse(list.ID)
+		filed, err := todos.CreateOne(ctx, Todo{Task: "Fix the build", ListID: &list.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, list.ID, *filed.ListID)
+		loose, _ := todos.CreateOne(ctx, Todo{Task: "Water the plants"})
+		page, err := todos.GetAll(ctx, TodoQuery{ListID: &listID})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
+		patched, err := todos.PatchOneByID(ctx, uuid.MustParse(loose.ID), TodoPatch{
+			ListID: Nullable[string]{Set: true, Value: &list.ID},
+		}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, list.ID, *patched.ListID)
+		_, err = lists.DeleteListByID(ctx, listID)
+		assert.Nil(t, err)
+		found, err := todos.GetOneByID(ctx, uuid.MustParse(filed.ID))
+		assert.Nil(t, err)
+		assert.Nil(t, found.ListID)
+		events, err := todos.GetHistory(ctx, uuid.MustParse(filed.ID))
+		assert.Nil(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, EventUpdated, events[1].Kind)
+		assert.JSONEq(t, `{"listId": "`+list.ID+`"}`, mustMarshal(t, events[1].Before))
+		assert.JSONEq(t, `{"listId": null}`, mustMarshal(t, events[1].After))
+	})
+
+	t.Run("tags todos", func(t *testing.T) {
+		_, todos, tags := newStores(t)
+		created, err := todos.CreateOne(ctx, Todo{Task: "Fix the build", Tags: []string{"CI", " backend", "ci"}})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"backend", "ci"}, created.Tags)
+		_, err = todos.CreateOne(ctx, Todo{Task: "Write the docs", Tags: []string{"docs", "backend"}})
+		assert.Nil(t, err)
+		page, err := todos.GetAll(ctx, TodoQuery{Tag: "Backend"})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 2)
+		id := uuid.MustParse(created.ID)
+		updated, err := todos.UpdateOneByID(ctx, id, Todo{Task: "Fix the build", Tags: []string{"ci", "urgent"}}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"ci", "urgent"}, updated.Tags)
+		patched, err := todos.PatchOneByID(ctx, id, TodoPatch{Task: &created.Task}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"ci", "urgent"}, patched.Tags)
+		page, err = todos.GetAll(ctx, TodoQuery{Tag: "backend"})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
+		counts, err := tags.GetTags(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, []Tag{
+			{Name: "backend", Count: 1},
+			{Name: "ci", Count: 1},
+			{Name: "docs", Count: 1},
+			{Name: "urgent", Count: 1},
+		}, counts)
+		counts, err = tags.GetTags(otherCtx)
+		assert.Nil(t, err)
+		assert.Len(t, counts, 0)
+	})
+}
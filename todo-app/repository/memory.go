@@ -0,0 +1,611 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+)
+
+type memoryTodo struct {
+	Todo
+	owner     uuid.UUID
+	deletedAt *time.Time
+}
+
+type MemoryTodoRepository struct {
+	mu     sync.RWMutex
+	todos  map[uuid.UUID]*memoryTodo
+	lists  map[uuid.UUID]*memoryList
+	events []TodoEvent
+	options
+}
+
+func NewMemoryTodoRepository(opts ...Option) *MemoryTodoRepository {
+	return &MemoryTodoRepository{
+		todos:   make(map[uuid.UUID]*memoryTodo),
+		lists:   make(map[uuid.UUID]*memoryList),
+		options: newOptions(opts),
+	}
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func touch(previous time.Time) time.Time {
+	if t := now(); t.After(previous) {
+		return t
+	}
+	return previous.Add(time.Microsecond)
+}
+
+func priorityOrNormal(p Priority) Priority {
+	if p == "" {
+		return PriorityNormal
+	}
+	return p
+}
+
+func complete(todo *Todo, isCompleted bool, at time.Time) {
+	switch {
+	case !isCompleted:
+		todo.CompletedAt = nil
+	case !todo.IsCompleted:
+		todo.CompletedAt = &at
+	}
+	todo.IsCompleted = isCompleted
+}
+
+func (r *MemoryTodoRepository) find(owner uuid.UUID, id uuid.UUID) *memoryTodo {
+	todo, ok := r.todos[id]
+	if !ok || todo.owner != owner || todo.deletedAt != nil {
+		return nil
+	}
+	return todo
+}
+
+func (r *MemoryTodoRepository) findList(owner uuid.UUID, id uuid.UUID) *memoryList {
+	list, ok := r.lists[id]
+	if !ok || list.owner != owner {
+		return nil
+	}
+	return list
+}
+
+func (r *MemoryTodoRepository) checkList(owner uuid.UUID, id *string) error {
+	if id == nil {
+		return nil
+	}
+	listID, err := uuid.Parse(*id)
+	if err != nil || r.findList(owner, listID) == nil {
+		return ErrListNotFound
+	}
+	return nil
+}
+
+func (r *MemoryTodoRepository) parentOf(owner uuid.UUID, id *string) *memoryTodo {
+	if id == nil {
+		return nil
+	}
+	parentID, err := uuid.Parse(*id)
+	if err != nil {
+		return nil
+	}
+	return r.find(owner, parentID)
+}
+
+func sameParent(a *string, b *string) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func (r *MemoryTodoRepository) children(owner uuid.UUID, parentID *string) []*memoryTodo {
+	children := make([]*memoryTodo, 0)
+	for _, todo := range r.todos {
+		if todo.owner == owner && todo.deletedAt == nil && sameParent(todo.ParentID, parentID) {
+			children = append(children, todo)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].Position != children[j].Position {
+			return children[i].Position < children[j].Position
+		}
+		return children[i].ID < children[j].ID
+	})
+	return children
+}
+
+func (r *MemoryTodoRepository) descendants(id string) []*memoryTodo {
+	var descendants []*memoryTodo
+	parents := map[string]bool{id: true}
+	for found := true; found; {
+		found = false
+		for _, todo := range r.todos {
+			if todo.ParentID != nil && parents[*todo.ParentID] && !parents[todo.ID] {
+				parents[todo.ID] = true
+				descendants = append(descendants, todo)
+				found = true
+			}
+		}
+	}
+	return descendants
+}
+
+func (r *MemoryTodoRepository) nextPosition(owner uuid.UUID, parentID *string) float64 {
+	var position float64
+	for _, todo := range r.todos {
+		if todo.owner == owner && sameParent(todo.ParentID, parentID) && todo.Position > position {
+			position = todo.Position
+		}
+	}
+	return position + PositionGap
+}
+
+func (r *MemoryTodoRepository) checkParent(owner uuid.UUID, id string, parentID *string) error {
+	if parentID == nil {
+		return nil
+	}
+	parent := r.parentOf(owner, parentID)
+	if parent == nil {
+		return ErrParentNotFound
+	}
+	for ancestor := &parent.Todo; ancestor != nil; {
+		if ancestor.ID == id {
+			return ErrCyclicParent
+		}
+		if ancestor.ParentID == nil {
+			break
+		}
+		next, ok := r.todos[uuid.MustParse(*ancestor.ParentID)]
+		if !ok {
+			break
+		}
+		ancestor = &next.Todo
+	}
+	return nil
+}
+
+func (r *MemoryTodoRepository) deriveCompletion(owner uuid.UUID, todo *memoryTodo) bool {
+	children := r.children(owner, &todo.ID)
+	if !todo.DeriveCompletion || len(children) == 0 {
+		return false
+	}
+	isCompleted := true
+	for _, child := range children {
+		isCompleted = isCompleted && child.IsCompleted
+	}
+	if isCompleted == todo.IsCompleted {
+		return false
+	}
+	todo.UpdatedAt = touch(todo.UpdatedAt)
+	complete(&todo.Todo, isCompleted, todo.UpdatedAt)
+	return true
+}
+
+func (r *MemoryTodoRepository) propagate(owner uuid.UUID, parentID *string) error {
+	for parent := r.parentOf(owner, parentID); parent != nil; parent = r.parentOf(owner, parent.ParentID) {
+		before := parent.Todo
+		if !r.deriveCompletion(owner, parent) {
+			return nil
+		}
+		if err := r.record(owner, EventUpdated, &before, parent.Todo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryTodoRepository) read(ctx context.Context, f func(owner uuid.UUID)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f(owner)
+	return nil
+}
+
+func (r *MemoryTodoRepository) write(ctx context.Context, f func(owner uuid.UUID) (*Todo, error)) (*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return f(owner)
+}
+
+func (r *MemoryTodoRepository) CreateOne(ctx context.Context, t Todo) (*Todo, error) {
+	return r.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		return r.createOne(owner, t)
+	})
+}
+
+func (r *MemoryTodoRepository) createOne(owner uuid.UUID, t Todo) (*Todo, error) {
+	if err := r.checkList(owner, t.ListID); err != nil {
+		return nil, err
+	}
+	if t.ParentID != nil && r.parentOf(owner, t.ParentID) == nil {
+		return nil, ErrParentNotFound
+	}
+	id := uuid.New()
+	created := now()
+	todo := Todo{
+		ID:               id.String(),
+		Task:             t.Task,
+		DueAt:            t.DueAt,
+		Priority:         priorityOrNormal(t.Priority),
+		ListID:           t.ListID,
+		Tags:             NormalizeTags(t.Tags),
+		ParentID:         t.ParentID,
+		Position:         r.nextPosition(owner, t.ParentID),
+		CreatedAt:        created,
+		UpdatedAt:        created,
+		DeriveCompletion: t.DeriveCompletion,
+	}
+	complete(&todo, t.IsCompleted, created)
+	if err := r.record(owner, EventCreated, nil, todo); err != nil {
+		return nil, err
+	}
+	r.todos[id] = &memoryTodo{Todo: todo, owner: owner}
+	if err := r.propagate(owner, todo.ParentID); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+func (r *MemoryTodoRepository) UpdateOneByID(ctx context.Context, id uuid.UUID, t Todo, ifMatch *time.Time) (*Todo, error) {
+	return r.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		return r.updateOneByID(owner, id, t, ifMatch)
+	})
+}
+
+func (r *MemoryTodoRepository) updateOneByID(owner uuid.UUID, id uuid.UUID, t Todo, ifMatch *time.Time) (*Todo, error) {
+	todo := r.find(owner, id)
+	if todo == nil {
+		return nil, nil
+	}
+	if !sameID(todo.ParentID, t.ParentID) {
+		return nil, ErrParentChanged
+	}
+	if ifMatch != nil && !todo.UpdatedAt.Equal(*ifMatch) {
+		return nil, ErrPreconditionFailed
+	}
+	return r.update(owner, todo, t, EventUpdated)
+}
+
+func sameID(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return strings.EqualFold(*a, *b)
+}
+
+func (r *MemoryTodoRepository) update(owner uuid.UUID, todo *memoryTodo, t Todo, kind TodoEventKind) (*Todo, error) {
+	if err := r.checkList(owner, t.ListID); err != nil {
+		return nil, err
+	}
+	before := todo.Todo
+	todo.Task = t.Task
+	todo.DueAt = t.DueAt
+	todo.Priority = priorityOrNormal(t.Priority)
+	todo.ListID = t.ListID
+	todo.Tags = NormalizeTags(t.Tags)
+	todo.DeriveCompletion = t.DeriveCompletion
+	todo.UpdatedAt = touch(todo.UpdatedAt)
+	complete(&todo.Todo, t.IsCompleted, todo.UpdatedAt)
+	r.deriveCompletion(owner, todo)
+	if err := r.propagate(owner, todo.ParentID); err != nil {
+		return nil, err
+	}
+	updated := todo.Todo
+	return &updated, r.record(owner, kind, &before, updated)
+}
+
+func (r *MemoryTodoRepository) PatchOneByID(ctx context.Context, id uuid.UUID, p TodoPatch, ifMatch *time.Time) (*Todo, error) {
+	return r.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		todo := r.find(owner, id)
+		if todo == nil {
+			return nil, nil
+		}
+		if ifMatch != nil && !todo.UpdatedAt.Equal(*ifMatch) {
+			return nil, ErrPreconditionFailed
+		}
+		before := todo.Todo
+		if p.ListID.Set {
+			if err := r.checkList(owner, p.ListID.Value); err != nil {
+				return nil, err
+			}
+			todo.ListID = p.ListID.Value
+		}
+		if p.Tags != nil {
+			todo.Tags = NormalizeTags(*p.Tags)
+		}
+		if p.Task != nil {
+			todo.Task = *p.Task
+		}
+		if p.DueAt.Set {
+			todo.DueAt = p.DueAt.Value
+		}
+		if p.Priority != nil {
+			todo.Priority = *p.Priority
+		}
+		if p.DeriveCompletion != nil {
+			todo.DeriveCompletion = *p.DeriveCompletion
+		}
+		todo.UpdatedAt = touch(todo.UpdatedAt)
+		if p.IsCompleted != nil {
+			complete(&todo.Todo, *p.IsCompleted, todo.UpdatedAt)
+		}
+		r.deriveCompletion(owner, todo)
+		if err := r.propagate(owner, todo.ParentID); err != nil {
+			return nil, err
+		}
+		patched := todo.Todo
+		return &patched, r.record(owner, EventUpdated, &before, patched)
+	})
+}
+
+func (r *MemoryTodoRepository) DeleteOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	return r.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		return r.deleteOneByID(owner, id)
+	})
+}
+
+func (r *MemoryTodoRepository) deleteOneByID(owner uuid.UUID, id uuid.UUID) (*Todo, error) {
+	todo := r.find(owner, id)
+	if todo == nil {
+		return nil, nil
+	}
+	deletedAt := now()
+	for _, descendant := range append(r.descendants(todo.ID), todo) {
+		switch {
+		case !r.softDelete:
+			delete(r.todos, uuid.MustParse(descendant.ID))
+		case descendant.deletedAt == nil:
+			descendant.deletedAt = &deletedAt
+		default:
+			continue
+		}
+		if err := r.record(owner, EventDeleted, &descendant.Todo, descendant.Todo); err != nil {
+			return nil, err
+		}
+	}
+	deleted := todo.Todo
+	return &deleted, r.propagate(owner, todo.ParentID)
+}
+
+func (r *MemoryTodoRepository) RestoreOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	return r.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		todo, ok := r.todos[id]
+		if !ok || todo.owner != owner || todo.deletedAt == nil {
+			return nil, nil
+		}
+		for _, descendant := range r.descendants(todo.ID) {
+			if descendant.deletedAt != nil && descendant.deletedAt.Equal(*todo.deletedAt) {
+				descendant.deletedAt = nil
+				descendant.UpdatedAt = touch(descendant.UpdatedAt)
+				if err := r.record(owner, EventRestored, &descendant.Todo, descendant.Todo); err != nil {
+					return nil, err
+				}
+			}
+		}
+		todo.deletedAt = nil
+		todo.UpdatedAt = touch(todo.UpdatedAt)
+		restored := todo.Todo
+		if err := r.record(owner, EventRestored, &restored, restored); err != nil {
+			return nil, err
+		}
+		return &restored, r.propagate(owner, todo.ParentID)
+	})
+}
+
+func (r *MemoryTodoRepository) MoveOneByID(ctx context.Context, id uuid.UUID, m TodoMove) (*Todo, error) {
+	return r.write(ctx, func(owner uuid.UUID) (*Todo, error) {
+		todo := r.find(owner, id)
+		if todo == nil {
+			return nil, nil
+		}
+		parentID := todo.ParentID
+		if m.ParentID.Set {
+			parentID = m.ParentID.Value
+			if err := r.checkParent(owner, todo.ID, parentID); err != nil {
+				return nil, err
+			}
+		}
+		siblings := make([]Sibling, 0)
+		for _, sibling := range r.children(owner, parentID) {
+			if sibling != todo {
+				siblings = append(siblings, Sibling{ID: sibling.ID, Position: sibling.Position})
+			}
+		}
+		position, renumbered, err := PlaceTodo(siblings, m)
+		if err != nil {
+			return nil, err
+		}
+		for _, sibling := range renumbered {
+			r.todos[uuid.MustParse(sibling.ID)].Position = sibling.Position
+		}
+		before := todo.Todo
+		todo.ParentID = parentID
+		todo.Position = position
+		todo.UpdatedAt = touch(todo.UpdatedAt)
+		if err := r.propagate(owner, before.ParentID); err != nil {
+			return nil, err
+		}
+		if err := r.propagate(owner, parentID); err != nil {
+			return nil, err
+		}
+		moved := todo.Todo
+		return &moved, r.record(owner, EventMoved, &before, moved)
+	})
+}
+
+func (r *MemoryTodoRepository) GetOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	todo := r.find(owner, id)
+	if todo == nil {
+		return nil, nil
+	}
+	found := todo.Todo
+	return &found, nil
+}
+
+func compareCursors(a TodoCursor, b TodoCursor) int {
+	if a.Rank != b.Rank {
+		return a.Rank - b.Rank
+	}
+	if a.Position != b.Position {
+		if a.Position < b.Position {
+			return -1
+		}
+		return 1
+	}
+	if c := a.Time.Compare(b.Time); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryTodoRepository) GetAll(ctx context.Context, q TodoQuery) (*TodoPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := todoSorts[q.Sort]; !ok {
+		q.Sort = SortCreatedAt
+	}
+	if q.Cursor != nil && q.Cursor.Sort != q.Sort {
+		return nil, ErrInvalidCursor
+	}
+	limit := q.Limit
+	if limit <= 0 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+	direction := 1
+	if strings.HasPrefix(string(q.Sort), "-") {
+		direction = -1
+	}
+	search := strings.ToLower(q.Search)
+	tag := NormalizeTag(q.Tag)
+	at := now()
+	r.mu.RLock()
+	all := make([]Todo, 0)
+	for _, todo := range r.todos {
+		switch {
+		case todo.owner != owner, todo.deletedAt != nil:
+		case q.IsCompleted != nil && todo.IsCompleted != *q.IsCompleted:
+		case q.Overdue != nil && todo.IsOverdue(at) != *q.Overdue:
+		case q.ListID != nil && (todo.ListID == nil || *todo.ListID != q.ListID.String()):
+		case q.ParentID != nil && (todo.ParentID == nil || *todo.ParentID != q.ParentID.String()):
+		case tag != "" && !hasTag(todo.Tags, tag):
+		case !strings.Contains(strings.ToLower(todo.Task), search):
+		case q.Cursor != nil && compareCursors(NewTodoCursor(q.Sort, todo.Todo), *q.Cursor)*direction <= 0:
+		default:
+			all = append(all, todo.Todo)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(all, func(i, j int) bool {
+		return compareCursors(NewTodoCursor(q.Sort, all[i]), NewTodoCursor(q.Sort, all[j]))*direction < 0
+	})
+	page := &TodoPage{Todos: all}
+	if len(all) > limit {
+		page.Todos = all[:limit]
+		next := NewTodoCursor(q.Sort, page.Todos[limit-1]).Encode()
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+func (r *MemoryTodoRepository) snapshot() map[uuid.UUID]*memoryTodo {
+	todos := make(map[uuid.UUID]*memoryTodo, len(r.todos))
+	for id, todo := range r.todos {
+		copied := *todo
+		todos[id] = &copied
+	}
+	return todos
+}
+
+func (r *MemoryTodoRepository) Batch(ctx context.Context, ops []TodoOperation, atomic bool) ([]TodoOperationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var snapshot map[uuid.UUID]*memoryTodo
+	if atomic {
+		snapshot = r.snapshot()
+	}
+	events := len(r.events)
+	results := make([]TodoOperationResult, len(ops))
+	for i, op := range ops {
+		results[i].Todo, results[i].Err = r.apply(owner, op)
+		if atomic && results[i].Err != nil {
+			r.todos = snapshot
+			r.events = r.events[:events]
+			abortBatch(results)
+			return results, nil
+		}
+	}
+	return results, nil
+}
+
+func (r *MemoryTodoRepository) apply(owner uuid.UUID, op TodoOperation) (*Todo, error) {
+	if op.Op == OpCreate {
+		return r.createOne(owner, *op.Todo)
+	}
+	id, err := uuid.Parse(op.ID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var todo *Todo
+	switch op.Op {
+	case OpUpdate:
+		todo, err = r.updateOneByID(owner, id, *op.Todo, nil)
+	case OpDelete:
+		todo, err = r.deleteOneByID(owner, id)
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", op.Op)
+	}
+	if err == nil && todo == nil {
+		return nil, ErrNotFound
+	}
+	return todo, err
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func (r *TodoRepository) MoveOneByID(ctx context.Context, id uuid.UUID, m TodoMove) (*Todo, error) {
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.audited(ctx, EventMoved, &id, func(tx *TodoRepository) (*Todo, error) {
+		var parent *string
+		err := tx.db.QueryRowContext(ctx, `--sql
+			select "parentTodoId"
+			  from "todos"
+			 where "todoId"    = $1
+			   and "userId"    = $2
+			   and "deletedAt" is null
+		`, id, owner).Scan(&parent)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		if m.ParentID.Set {
+			parent = m.ParentID.Value
+			if err := tx.checkParent(ctx, id, owner, parent); err != nil {
+				return nil, err
+			}
+		}
+		siblings, err := tx.siblings(ctx, id, owner, parent)
+		if err != nil {
+			return nil, err
+		}
+		position, renumbered, err := PlaceTodo(siblings, m)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.savePositions(ctx, renumbered); err != nil {
+			return nil, err
+		}
+		return scanTodo(tx.db.QueryRowContext(ctx, `--sql
+			update "todos"
+			   set "parentTodoId" = $1,
+				   "position"     = $2,
+				   "updatedAt"    = clock_timestamp()
+			 where "todoId"       = $3
+			returning `+todoColumns+`, `+tagsOf(`"todos"`)+`
+		`, parent, position, id))
+	}, Serializable())
+}
+
+func (r *TodoRepository) checkParent(ctx context.Context, id uuid.UUID, owner uuid.UUID, parent *string) error {
+	if parent == nil {
+		return nil
+	}
+	var exists, cyclic bool
+	err := r.db.QueryRowContext(ctx, `--sql
+		with recursive "ancestors" as (
+			select "todoId",
+				   "parentTodoId"
+			  from "todos"
+			 where "todoId"    = $1
+			   and "userId"    = $2
+			   and "deletedAt" is null
+			 union all
+			select "todos"."todoId",
+				   "todos"."parentTodoId"
+			  from "todos"
+			  join "ancestors" on "todos"."todoId" = "ancestors"."parentTodoId"
+		)
+		select count(*) > 0,
+			   coalesce(bool_or("todoId" = $3), false)
+		  from "ancestors"
+	`, *parent, owner, id).Scan(&exists, &cyclic)
+	switch {
+	case err != nil:
+		return fmt.Errorf("scanning row: %w", err)
+	case !exists:
+		return ErrParentNotFound
+	case cyclic:
+		return ErrCyclicParent
+	default:
+		return nil
+	}
+}
+
+func (r *TodoRepository) siblings(ctx context.Context, id uuid.UUID, owner uuid.UUID, parent *string) ([]Sibling, error) {
+	rows, err := r.db.QueryContext(ctx, `--sql
+		select "todoId",
+			   "position"
+		  from "todos"
+		 where "userId"       = $1
+		   and "parentTodoId" is not distinct from $2::uuid
+		   and "deletedAt"    is null
+		   and "todoId"       <> $3
+		 order by "position", "todoId"
+	`, owner, parent, id)
+	if err != nil {
+		return nil, fmt.Errorf("querying database: %w", err)
+	}
+	defer rows.Close()
+	var siblings []Sibling
+	for rows.Next() {
+		var sibling Sibling
+		if err := rows.Scan(&sibling.ID, &sibling.Position); err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		siblings = append(siblings, sibling)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return siblings, nil
+}
+
+func (r *TodoRepository) savePositions(ctx context.Context, siblings []Sibling) error {
+	if len(siblings) == 0 {
+		return nil
+	}
+	ids := make([]string, len(siblings))
+	positions := make([]float64, len(siblings))
+	for i, sibling := range siblings {
+		ids[i], positions[i] = sibling.ID, sibling.Position
+	}
+	_, err := r.db.ExecContext(ctx, `--sql
+		update "todos"
+		   set "position" = "renumbered"."position"
+		  from unnest($1::uuid[], $2::double precision[]) as "renumbered" ("todoId", "position")
+		 where "todos"."todoId" = "renumbered"."todoId"
+	`, pq.Array(ids), pq.Array(positions))
+	if err != nil {
+		return fmt.Errorf("renumbering positions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+)
+
+func searchLimit(limit int) int {
+	if limit <= 0 || limit > MaxLimit {
+		return DefaultLimit
+	}
+	return limit
+}
+
+// escapeHTML escapes a text column the way html.EscapeString does, so that
+// snippets only ever contain the <mark> tags added around matches.
+func escapeHTML(column string) string {
+	return `replace(replace(replace(replace(replace(` + column + `, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`
+}
+
+func highlight(task string, matches [][]int) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(html.EscapeString(task[last:m[0]]))
+		b.WriteString("<mark>" + html.EscapeString(task[m[0]:m[1]]) + "</mark>")
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(task[last:]))
+	return b.String()
+}
+
+func (r *TodoRepository) Search(ctx context.Context, q TodoSearch) ([]TodoSearchResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `--sql
+		select `+todoColumns+`, `+tagsOf(`"todos"`)+`,
+			   ts_rank("searchVector", "query") as "rank",
+			   ts_headline('english', `+escapeHTML(`"task"`)+`, "query", 'StartSel=<mark>, StopSel=</mark>')
+		  from "todos",
+			   websearch_to_tsquery('english', $1) as "query"
+		 where "userId"       = $2
+		   and "deletedAt"    is null
+		   and "searchVector" @@ "query"
+		 order by "rank" desc, "todoId"
+		 limit $3
+	`, q.Query, owner, searchLimit(q.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("querying database: %w", err)
+	}
+	defer rows.Close()
+	results := make([]TodoSearchResult, 0)
+	for rows.Next() {
+		var result TodoSearchResult
+		if err := rows.Scan(append(todoFields(&result.Todo), &result.Rank, &result.Snippet)...); err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return results, nil
+}
+
+// Search has no stemming or ranking to lean on in memory, so it matches the
+// query as a case-insensitive substring, the way ILIKE would, and ranks todos
+// by how often it occurs.
+func (r *MemoryTodoRepository) Search(ctx context.Context, q TodoSearch) ([]TodoSearchResult, error) {
+	results := make([]TodoSearchResult, 0)
+	pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(q.Query))
+	err := r.read(ctx, func(owner uuid.UUID) {
+		for _, todo := range r.todos {
+			if todo.owner != owner || todo.deletedAt != nil || q.Query == "" {
+				continue
+			}
+			if matches := pattern.FindAllStringIndex(todo.Task, -1); len(matches) > 0 {
+				results = append(results, TodoSearchResult{
+					Todo:    todo.Todo,
+					Rank:    float64(len(matches)),
+					Snippet: highlight(todo.Task, matches),
+				})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Todo.ID < results[j].Todo.ID
+	})
+	if limit := searchLimit(q.Limit); len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
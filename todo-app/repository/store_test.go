@@ -0,0 +1,664 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type newStore func(t *testing.T, opts ...Option) TodoStore
+
+func TestMemoryTodoStore(t *testing.T) {
+	testTodoStore(t, func(t *testing.T, opts ...Option) TodoStore {
+		return NewMemoryTodoRepository(opts...)
+	})
+}
+
+func TestPostgresTodoStore(t *testing.T) {
+	testTodoStore(t, func(t *testing.T, opts ...Option) TodoStore {
+		return NewTodoRepository(beginTx(t), opts...)
+	})
+}
+
+func testTodoStore(t *testing.T, newStore newStore) {
+	t.Run("gets no todos from an empty store", func(t *testing.T) {
+		s := newStore(t)
+		page, err := s.GetAll(ctx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 0)
+		assert.Nil(t, page.NextCursor)
+	})
+
+	t.Run("gets nil for a missing todo", func(t *testing.T) {
+		s := newStore(t)
+		id := uuid.New()
+		task := "Accept Go"
+		todo, err := s.GetOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+		todo, err = s.UpdateOneByID(ctx, id, Todo{Task: task}, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+		todo, err = s.PatchOneByID(ctx, id, TodoPatch{Task: &task}, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+		todo, err = s.DeleteOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+		todo, err = s.RestoreOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+		todo, err = s.MoveOneByID(ctx, id, TodoMove{})
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+	})
+
+	t.Run("creates and gets one todo", func(t *testing.T) {
+		s := newStore(t)
+		created, err := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		assert.Nil(t, err)
+		assert.Equal(t, "Learn Go", created.Task)
+		assert.False(t, created.IsCompleted)
+		assert.False(t, created.CreatedAt.IsZero())
+		found, err := s.GetOneByID(ctx, uuid.MustParse(created.ID))
+		assert.Nil(t, err)
+		assert.Equal(t, created.ID, found.ID)
+		assert.Equal(t, created.Task, found.Task)
+	})
+
+	t.Run("replaces one todo", func(t *testing.T) {
+		s := newStore(t)
+		created, _ := s.CreateOne(ctx, Todo{Task: "Learn Go", IsCompleted: true})
+		updated, err := s.UpdateOneByID(ctx, uuid.MustParse(created.ID), Todo{Task: "Accept Go"}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "Accept Go", updated.Task)
+		assert.False(t, updated.IsCompleted)
+	})
+
+	t.Run("patches only supplied fields", func(t *testing.T) {
+		s := newStore(t)
+		created, _ := s.CreateOne(ctx, Todo{Task: "Learn Go", IsCompleted: true})
+		id := uuid.MustParse(created.ID)
+		task := "Accept Go"
+		patched, err := s.PatchOneByID(ctx, id, TodoPatch{Task: &task}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "Accept Go", patched.Task)
+		assert.True(t, patched.IsCompleted)
+		isCompleted := false
+		patched, err = s.PatchOneByID(ctx, id, TodoPatch{IsCompleted: &isCompleted}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "Accept Go", patched.Task)
+		assert.False(t, patched.IsCompleted)
+	})
+
+	t.Run("updates only a matching version", func(t *testing.T) {
+		s := newStore(t)
+		created, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		id := uuid.MustParse(created.ID)
+		stale := created.UpdatedAt.Add(-time.Second)
+		task := "Accept Go"
+		updated, err := s.UpdateOneByID(ctx, id, Todo{Task: task}, &stale)
+		assert.ErrorIs(t, err, ErrPreconditionFailed)
+		assert.Nil(t, updated)
+		patched, err := s.PatchOneByID(ctx, id, TodoPatch{Task: &task}, &stale)
+		assert.ErrorIs(t, err, ErrPreconditionFailed)
+		assert.Nil(t, patched)
+		updated, err = s.UpdateOneByID(ctx, id, Todo{Task: task}, &created.UpdatedAt)
+		assert.Nil(t, err)
+		assert.Equal(t, task, updated.Task)
+		missing, err := s.UpdateOneByID(ctx, uuid.New(), Todo{Task: task}, &stale)
+		assert.Nil(t, err)
+		assert.Nil(t, missing)
+	})
+
+	t.Run("hard deletes one todo", func(t *testing.T) {
+		s := newStore(t)
+		created, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		id := uuid.MustParse(created.ID)
+		deleted, err := s.DeleteOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, created.ID, deleted.ID)
+		found, err := s.GetOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, found)
+		restored, err := s.RestoreOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, restored)
+	})
+
+	t.Run("soft deletes and restores one todo", func(t *testing.T) {
+		s := newStore(t, WithSoftDelete())
+		created, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		id := uuid.MustParse(created.ID)
+		_, err := s.DeleteOneByID(ctx, id)
+		assert.Nil(t, err)
+		page, err := s.GetAll(ctx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 0)
+		updated, err := s.UpdateOneByID(ctx, id, Todo{Task: "Accept Go"}, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, updated)
+		restored, err := s.RestoreOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, "Learn Go", restored.Task)
+		found, err := s.GetOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, created.ID, found.ID)
+	})
+
+	t.Run("paginates in sort order", func(t *testing.T) {
+		for _, sortBy := range []TodoSort{
+			SortCreatedAt, SortCreatedAtDesc, SortUpdatedAt, SortUpdatedAtDesc, SortPriority, SortPriorityDesc,
+			SortPosition, SortPositionDesc,
+		} {
+			s := newStore(t)
+			for i, task := range []string{"a", "b", "c", "d", "e"} {
+				priority := []Priority{PriorityHigh, PriorityLow, PriorityUrgent, PriorityHigh, ""}[i]
+				_, err := s.CreateOne(ctx, Todo{Task: task, Priority: priority})
+				assert.Nil(t, err)
+			}
+			var seen []Todo
+			q := TodoQuery{Limit: 2, Sort: sortBy}
+			for pages := 0; pages < 3; pages++ {
+				page, err := s.GetAll(ctx, q)
+				assert.Nil(t, err)
+				seen = append(seen, page.Todos...)
+				if page.NextCursor == nil {
+					break
+				}
+				q.Cursor, err = DecodeTodoCursor(*page.NextCursor)
+				assert.Nil(t, err)
+			}
+			assert.Len(t, seen, 5, sortBy)
+			assert.True(t, sort.SliceIsSorted(seen, func(i, j int) bool {
+				c := compareCursors(NewTodoCursor(sortBy, seen[i]), NewTodoCursor(sortBy, seen[j]))
+				if strings.HasPrefix(string(sortBy), "-") {
+					return c > 0
+				}
+				return c < 0
+			}), sortBy)
+		}
+	})
+
+	t.Run("rejects a cursor from another sort", func(t *testing.T) {
+		s := newStore(t)
+		cursor := NewTodoCursor(SortUpdatedAt, Todo{ID: uuid.NewString()})
+		_, err := s.GetAll(ctx, TodoQuery{Sort: SortCreatedAt, Cursor: &cursor})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+
+	t.Run("filters by completion and search", func(t *testing.T) {
+		s := newStore(t)
+		for _, todo := range []Todo{
+			{Task: "Learn Go", IsCompleted: true},
+			{Task: "Learn 100% of Go"},
+			{Task: "Try a Somersault"},
+			{Task: "snake_case"},
+		} {
+			_, err := s.CreateOne(ctx, todo)
+			assert.Nil(t, err)
+		}
+		isCompleted := false
+		page, err := s.GetAll(ctx, TodoQuery{IsCompleted: &isCompleted})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 3)
+		page, err = s.GetAll(ctx, TodoQuery{Search: "go"})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 2)
+		page, err = s.GetAll(ctx, TodoQuery{Search: "100%"})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
+		page, err = s.GetAll(ctx, TodoQuery{Search: "e_c"})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
+		page, err = s.GetAll(ctx, TodoQuery{Search: "e_c", IsCompleted: &isCompleted})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
+	})
+
+	t.Run("tracks when a todo was completed", func(t *testing.T) {
+		s := newStore(t)
+		created, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		assert.Nil(t, created.CompletedAt)
+		assert.Equal(t, PriorityNormal, created.Priority)
+		id := uuid.MustParse(created.ID)
+		completed, err := s.UpdateOneByID(ctx, id, Todo{Task: "Learn Go", IsCompleted: true}, nil)
+		assert.Nil(t, err)
+		assert.NotNil(t, completed.CompletedAt)
+		updated, err := s.UpdateOneByID(ctx, id, Todo{Task: "Master Go", IsCompleted: true}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, completed.CompletedAt, updated.CompletedAt)
+		isCompleted := false
+		patched, err := s.PatchOneByID(ctx, id, TodoPatch{IsCompleted: &isCompleted}, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, patched.CompletedAt)
+		isCompleted = true
+		patched, err = s.PatchOneByID(ctx, id, TodoPatch{IsCompleted: &isCompleted}, nil)
+		assert.Nil(t, err)
+		assert.NotNil(t, patched.CompletedAt)
+		created, _ = s.CreateOne(ctx, Todo{Task: "Teach Go", IsCompleted: true})
+		assert.NotNil(t, created.CompletedAt)
+	})
+
+	t.Run("patches the due date and priority", func(t *testing.T) {
+		s := newStore(t)
+		dueAt := time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond)
+		created, _ := s.CreateOne(ctx, Todo{Task: "Learn Go", DueAt: &dueAt, Priority: PriorityLow})
+		id := uuid.MustParse(created.ID)
+		assert.True(t, dueAt.Equal(*created.DueAt))
+		urgent := PriorityUrgent
+		patched, err := s.PatchOneByID(ctx, id, TodoPatch{Priority: &urgent}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, PriorityUrgent, patched.Priority)
+		assert.True(t, dueAt.Equal(*patched.DueAt))
+		patched, err = s.PatchOneByID(ctx, id, TodoPatch{DueAt: Nullable[time.Time]{Set: true}}, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, patched.DueAt)
+		assert.Equal(t, PriorityUrgent, patched.Priority)
+	})
+
+	t.Run("filters overdue todos", func(t *testing.T) {
+		s := newStore(t)
+		past := time.Now().Add(-time.Hour)
+		future := time.Now().Add(time.Hour)
+		for _, todo := range []Todo{
+			{Task: "Learn Go", DueAt: &past},
+			{Task: "Accept Go", DueAt: &past, IsCompleted: true},
+			{Task: "Master Go", DueAt: &future},
+			{Task: "Teach Go"},
+		} {
+			_, err := s.CreateOne(ctx, todo)
+			assert.Nil(t, err)
+		}
+		overdue := true
+		page, err := s.GetAll(ctx, TodoQuery{Overdue: &overdue})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
+		assert.Equal(t, "Learn Go", page.Todos[0].Task)
+		overdue = false
+		page, err = s.GetAll(ctx, TodoQuery{Overdue: &overdue})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 3)
+	})
+
+	t.Run("nests and reorders subtasks", func(t *testing.T) {
+		s := newStore(t)
+		parent, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		parentID := uuid.MustParse(parent.ID)
+		var tasks []*Todo
+		for _, task := range []string{"Read the tour", "Write a server", "Ship it"} {
+			todo, err := s.CreateOne(ctx, Todo{Task: task, ParentID: &parent.ID})
+			assert.Nil(t, err)
+			assert.Equal(t, parent.ID, *todo.ParentID)
+			tasks = append(tasks, todo)
+		}
+		assert.Less(t, tasks[0].Position, tasks[1].Position)
+		moved, err := s.MoveOneByID(ctx, uuid.MustParse(tasks[2].ID), TodoMove{Before: &tasks[0].ID})
+		assert.Nil(t, err)
+		assert.Less(t, moved.Position, tasks[0].Position)
+		moved, err = s.MoveOneByID(ctx, uuid.MustParse(tasks[0].ID), TodoMove{After: &tasks[1].ID})
+		assert.Nil(t, err)
+		assert.Greater(t, moved.Position, tasks[1].Position)
+		page, err := s.GetAll(ctx, TodoQuery{ParentID: &parentID, Sort: SortPosition})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Ship it", "Write a server", "Read the tour"}, tasksOf(page.Todos))
+		moved, err = s.MoveOneByID(ctx, uuid.MustParse(tasks[1].ID), TodoMove{ParentID: Nullable[string]{Set: true}})
+		assert.Nil(t, err)
+		assert.Nil(t, moved.ParentID)
+		page, err = s.GetAll(ctx, TodoQuery{ParentID: &parentID})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 2)
+		_, err = s.MoveOneByID(ctx, uuid.MustParse(tasks[0].ID), TodoMove{After: &tasks[1].ID})
+		assert.ErrorIs(t, err, ErrSiblingNotFound)
+	})
+
+	t.Run("renumbers siblings when positions run out", func(t *testing.T) {
+		s := newStore(t)
+		first, _ := s.CreateOne(ctx, Todo{Task: "a"})
+		second, _ := s.CreateOne(ctx, Todo{Task: "b"})
+		third, _ := s.CreateOne(ctx, Todo{Task: "c"})
+		for i := 0; i < 100; i++ {
+			moving := []*Todo{second, third}[i%2]
+			_, err := s.MoveOneByID(ctx, uuid.MustParse(moving.ID), TodoMove{After: &first.ID})
+			assert.Nil(t, err)
+		}
+		page, err := s.GetAll(ctx, TodoQuery{Sort: SortPosition})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "c", "b"}, tasksOf(page.Todos))
+		assert.Less(t, page.Todos[0].Position, page.Todos[1].Position)
+		assert.Less(t, page.Todos[1].Position, page.Todos[2].Position)
+	})
+
+	t.Run("rejects a missing or cyclic parent", func(t *testing.T) {
+		s := newStore(t)
+		missing := uuid.NewString()
+		_, err := s.CreateOne(ctx, Todo{Task: "Learn Go", ParentID: &missing})
+		assert.ErrorIs(t, err, ErrParentNotFound)
+		parent, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		child, _ := s.CreateOne(ctx, Todo{Task: "Read the tour", ParentID: &parent.ID})
+		_, err = s.MoveOneByID(ctx, uuid.MustParse(parent.ID), TodoMove{ParentID: Nullable[string]{Set: true, Value: &child.ID}})
+		assert.ErrorIs(t, err, ErrCyclicParent)
+		_, err = s.MoveOneByID(ctx, uuid.MustParse(parent.ID), TodoMove{ParentID: Nullable[string]{Set: true, Value: &parent.ID}})
+		assert.ErrorIs(t, err, ErrCyclicParent)
+		_, err = s.MoveOneByID(ctx, uuid.MustParse(child.ID), TodoMove{ParentID: Nullable[string]{Set: true, Value: &missing}})
+		assert.ErrorIs(t, err, ErrParentNotFound)
+		_, err = s.CreateOne(otherCtx, Todo{Task: "Learn Go", ParentID: &parent.ID})
+		assert.ErrorIs(t, err, ErrParentNotFound)
+	})
+
+	t.Run("reparents only by moving", func(t *testing.T) {
+		s := newStore(t)
+		parent, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		child, _ := s.CreateOne(ctx, Todo{Task: "Read the tour", ParentID: &parent.ID})
+		childID := uuid.MustParse(child.ID)
+		_, err := s.UpdateOneByID(ctx, childID, Todo{Task: "Read the spec"}, nil)
+		assert.ErrorIs(t, err, ErrParentChanged)
+		updated, err := s.UpdateOneByID(ctx, childID, Todo{Task: "Read the spec", ParentID: &parent.ID}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, &parent.ID, updated.ParentID)
+	})
+
+	t.Run("cascades deletes to subtasks", func(t *testing.T) {
+		for _, opts := range [][]Option{nil, {WithSoftDelete()}} {
+			s := newStore(t, opts...)
+			parent, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+			child, _ := s.CreateOne(ctx, Todo{Task: "Read the tour", ParentID: &parent.ID})
+			grandchild, _ := s.CreateOne(ctx, Todo{Task: "Finish the tour", ParentID: &child.ID})
+			_, err := s.DeleteOneByID(ctx, uuid.MustParse(parent.ID))
+			assert.Nil(t, err)
+			found, err := s.GetOneByID(ctx, uuid.MustParse(grandchild.ID))
+			assert.Nil(t, err)
+			assert.Nil(t, found)
+			if len(opts) == 0 {
+				continue
+			}
+			_, err = s.RestoreOneByID(ctx, uuid.MustParse(parent.ID))
+			assert.Nil(t, err)
+			found, err = s.GetOneByID(ctx, uuid.MustParse(grandchild.ID))
+			assert.Nil(t, err)
+			assert.Equal(t, grandchild.ID, found.ID)
+		}
+	})
+
+	t.Run("derives completion from subtasks", func(t *testing.T) {
+		s := newStore(t)
+		parent, _ := s.CreateOne(ctx, Todo{Task: "Learn Go", DeriveCompletion: true})
+		parentID := uuid.MustParse(parent.ID)
+		first, _ := s.CreateOne(ctx, Todo{Task: "Read the tour", ParentID: &parent.ID})
+		second, _ := s.CreateOne(ctx, Todo{Task: "Write a server", ParentID: &parent.ID, IsCompleted: true})
+		found, _ := s.GetOneByID(ctx, parentID)
+		assert.False(t, found.IsCompleted)
+		done := true
+		_, err := s.PatchOneByID(ctx, uuid.MustParse(first.ID), TodoPatch{IsCompleted: &done}, nil)
+		assert.Nil(t, err)
+		found, _ = s.GetOneByID(ctx, parentID)
+		assert.True(t, found.IsCompleted)
+		assert.NotNil(t, found.CompletedAt)
+		updated, err := s.UpdateOneByID(ctx, parentID, Todo{Task: "Learn Go", DeriveCompletion: true}, nil)
+		assert.Nil(t, err)
+		assert.True(t, updated.IsCompleted)
+		third, _ := s.CreateOne(ctx, Todo{Task: "Ship it", ParentID: &parent.ID})
+		found, _ = s.GetOneByID(ctx, parentID)
+		assert.False(t, found.IsCompleted)
+		assert.Nil(t, found.CompletedAt)
+		_, err = s.DeleteOneByID(ctx, uuid.MustParse(third.ID))
+		assert.Nil(t, err)
+		found, _ = s.GetOneByID(ctx, parentID)
+		assert.True(t, found.IsCompleted)
+		_, err = s.MoveOneByID(ctx, uuid.MustParse(second.ID), TodoMove{ParentID: Nullable[string]{Set: true}})
+		assert.Nil(t, err)
+		undone := false
+		_, err = s.PatchOneByID(ctx, uuid.MustParse(first.ID), TodoPatch{IsCompleted: &undone}, nil)
+		assert.Nil(t, err)
+		found, _ = s.GetOneByID(ctx, parentID)
+		assert.False(t, found.IsCompleted)
+	})
+
+	t.Run("searches tasks by relevance", func(t *testing.T) {
+		s := newStore(t, WithSoftDelete())
+		for _, task := range []string{"Plan the garden", "Weed the garden and water the garden", "Water the plants"} {
+			_, err := s.CreateOne(ctx, Todo{Task: task})
+			assert.Nil(t, err)
+		}
+		deleted, _ := s.CreateOne(ctx, Todo{Task: "Fence the garden"})
+		_, err := s.DeleteOneByID(ctx, uuid.MustParse(deleted.ID))
+		assert.Nil(t, err)
+		_, err = s.CreateOne(otherCtx, Todo{Task: "Dig the garden"})
+		assert.Nil(t, err)
+		results, err := s.Search(ctx, TodoSearch{Query: "Garden"})
+		assert.Nil(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "Weed the garden and water the garden", results[0].Todo.Task)
+		assert.Greater(t, results[0].Rank, results[1].Rank)
+		assert.Contains(t, results[1].Snippet, "<mark>garden</mark>")
+		results, err = s.Search(ctx, TodoSearch{Query: "garden", Limit: 1})
+		assert.Nil(t, err)
+		assert.Len(t, results, 1)
+		results, err = s.Search(ctx, TodoSearch{Query: "orchard"})
+		assert.Nil(t, err)
+		assert.Len(t, results, 0)
+		_, err = s.CreateOne(ctx, Todo{Task: `Fix <script>alert("x")</script> bug`})
+		assert.Nil(t, err)
+		results, err = s.Search(ctx, TodoSearch{Query: "bug"})
+		assert.Nil(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "Fix &lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>bug</mark>", results[0].Snippet)
+	})
+
+	t.Run("records history and reverts", func(t *testing.T) {
+		s := newStore(t)
+		created, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		id := uuid.MustParse(created.ID)
+		_, err := s.UpdateOneByID(ctx, id, Todo{Task: "Accept Go"}, nil)
+		assert.Nil(t, err)
+		priority := PriorityHigh
+		_, err = s.PatchOneByID(ctx, id, TodoPatch{Priority: &priority}, nil)
+		assert.Nil(t, err)
+		events, err := s.GetHistory(ctx, id)
+		assert.Nil(t, err)
+		assert.Len(t, events, 3)
+		assert.Equal(t, EventCreated, events[0].Kind)
+		assert.Nil(t, events[0].Before)
+		assert.JSONEq(t, `"Learn Go"`, string(events[0].After["task"]))
+		assert.Equal(t, EventUpdated, events[1].Kind)
+		assert.Equal(t, testUserID.String(), events[1].ActorID)
+		assert.JSONEq(t, `{"task": "Learn Go"}`, mustMarshal(t, events[1].Before))
+		assert.JSONEq(t, `{"task": "Accept Go"}`, mustMarshal(t, events[1].After))
+		firstEvent := events[0].ID
+		reverted, err := s.RevertOneByID(ctx, id, firstEvent)
+		assert.Nil(t, err)
+		assert.Equal(t, "Learn Go", reverted.Task)
+		assert.Equal(t, PriorityNormal, reverted.Priority)
+		events, err = s.GetHistory(ctx, id)
+		assert.Nil(t, err)
+		assert.Len(t, events, 4)
+		assert.Equal(t, EventReverted, events[3].Kind)
+		other, _ := s.CreateOne(ctx, Todo{Task: "Teach Go"})
+		_, err = s.RevertOneByID(ctx, uuid.MustParse(other.ID), events[1].ID)
+		assert.ErrorIs(t, err, ErrEventNotFound)
+		events, err = s.GetHistory(otherCtx, id)
+		assert.Nil(t, err)
+		assert.Len(t, events, 0)
+		_, err = s.MoveOneByID(ctx, id, TodoMove{ParentID: Nullable[string]{Set: true, Value: &other.ID}})
+		assert.Nil(t, err)
+		_, err = s.RevertOneByID(ctx, id, firstEvent)
+		assert.ErrorIs(t, err, ErrRevertCrossesMove)
+		found, _ := s.GetOneByID(ctx, id)
+		assert.Equal(t, &other.ID, found.ParentID)
+	})
+
+	t.Run("keeps the history of a hard-deleted todo", func(t *testing.T) {
+		s := newStore(t)
+		created, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		id := uuid.MustParse(created.ID)
+		_, err := s.DeleteOneByID(ctx, id)
+		assert.Nil(t, err)
+		found, _ := s.GetOneByID(ctx, id)
+		assert.Nil(t, found)
+		events, err := s.GetHistory(ctx, id)
+		assert.Nil(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, EventDeleted, events[1].Kind)
+	})
+
+	t.Run("records derived and cascaded changes", func(t *testing.T) {
+		s := newStore(t, WithSoftDelete())
+		parent, _ := s.CreateOne(ctx, Todo{Task: "Learn Go", DeriveCompletion: true})
+		parentID := uuid.MustParse(parent.ID)
+		child, _ := s.CreateOne(ctx, Todo{Task: "Read the tour", ParentID: &parent.ID})
+		childID := uuid.MustParse(child.ID)
+		done := true
+		_, err := s.PatchOneByID(ctx, childID, TodoPatch{IsCompleted: &done}, nil)
+		assert.Nil(t, err)
+		events, err := s.GetHistory(ctx, parentID)
+		assert.Nil(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, EventUpdated, events[1].Kind)
+		assert.JSONEq(t, `true`, string(events[1].After["isCompleted"]))
+		_, err = s.DeleteOneByID(ctx, parentID)
+		assert.Nil(t, err)
+		_, err = s.RestoreOneByID(ctx, parentID)
+		assert.Nil(t, err)
+		events, err = s.GetHistory(ctx, childID)
+		assert.Nil(t, err)
+		kinds := make([]TodoEventKind, len(events))
+		for i, event := range events {
+			kinds[i] = event.Kind
+		}
+		assert.Equal(t, []TodoEventKind{EventCreated, EventUpdated, EventDeleted, EventRestored}, kinds)
+	})
+
+	t.Run("applies a batch of operations", func(t *testing.T) {
+		s := newStore(t)
+		updated, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		deleted, _ := s.CreateOne(ctx, Todo{Task: "Accept Go"})
+		results, err := s.Batch(ctx, []TodoOperation{
+			{Op: OpUpdate, ID: updated.ID, Todo: &Todo{Task: "Master Go", IsCompleted: true}},
+			{Op: OpCreate, Todo: &Todo{Task: "Teach Go"}},
+			{Op: OpDelete, ID: deleted.ID},
+			{Op: OpDelete, ID: uuid.New().String()},
+		}, false)
+		assert.Nil(t, err)
+		assert.Len(t, results, 4)
+		assert.Nil(t, results[0].Err)
+		assert.Equal(t, "Master Go", results[0].Todo.Task)
+		assert.Nil(t, results[1].Err)
+		assert.Equal(t, "Teach Go", results[1].Todo.Task)
+		assert.Nil(t, results[2].Err)
+		assert.ErrorIs(t, results[3].Err, ErrNotFound)
+		page, err := s.GetAll(ctx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 2)
+	})
+
+	t.Run("rolls back an atomic batch", func(t *testing.T) {
+		s := newStore(t)
+		existing, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		results, err := s.Batch(ctx, []TodoOperation{
+			{Op: OpCreate, Todo: &Todo{Task: "Teach Go"}},
+			{Op: OpUpdate, ID: existing.ID, Todo: &Todo{Task: "Master Go"}},
+			{Op: OpDelete, ID: uuid.New().String()},
+		}, true)
+		assert.Nil(t, err)
+		assert.ErrorIs(t, results[0].Err, ErrBatchAborted)
+		assert.ErrorIs(t, results[1].Err, ErrBatchAborted)
+		assert.ErrorIs(t, results[2].Err, ErrNotFound)
+		page, err := s.GetAll(ctx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
+		assert.Equal(t, "Learn Go", page.Todos[0].Task)
+		events, err := s.GetHistory(ctx, uuid.MustParse(existing.ID))
+		assert.Nil(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("fails only the bad operations of a non-atomic batch", func(t *testing.T) {
+		s := newStore(t)
+		missing := uuid.NewString()
+		results, err := s.Batch(ctx, []TodoOperation{
+			{Op: OpCreate, Todo: &Todo{Task: "Read the tour", ParentID: &missing}},
+			{Op: OpCreate, Todo: &Todo{Task: "Learn Go"}},
+		}, false)
+		assert.Nil(t, err)
+		assert.ErrorIs(t, results[0].Err, ErrParentNotFound)
+		assert.Nil(t, results[1].Err)
+		assert.Equal(t, "Learn Go", results[1].Todo.Task)
+	})
+
+	t.Run("rejects a batch create under a deleted parent", func(t *testing.T) {
+		s := newStore(t, WithSoftDelete())
+		parent, _ := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		_, err := s.DeleteOneByID(ctx, uuid.MustParse(parent.ID))
+		assert.Nil(t, err)
+		results, err := s.Batch(ctx, []TodoOperation{
+			{Op: OpCreate, Todo: &Todo{Task: "Read the tour", ParentID: &parent.ID}},
+		}, false)
+		assert.Nil(t, err)
+		assert.ErrorIs(t, results[0].Err, ErrParentNotFound)
+		page, err := s.GetAll(ctx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 0)
+	})
+
+	t.Run("scopes todos to their owner", func(t *testing.T) {
+		s := newStore(t)
+		created, err := s.CreateOne(ctx, Todo{Task: "Learn Go"})
+		assert.Nil(t, err)
+		id := uuid.MustParse(created.ID)
+		todo, err := s.GetOneByID(otherCtx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+		todo, err = s.UpdateOneByID(otherCtx, id, Todo{Task: "Mine now"}, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+		todo, err = s.DeleteOneByID(otherCtx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+		page, err := s.GetAll(otherCtx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 0)
+		results, err := s.Batch(otherCtx, []TodoOperation{{Op: OpDelete, ID: created.ID}}, false)
+		assert.Nil(t, err)
+		assert.ErrorIs(t, results[0].Err, ErrNotFound)
+		todo, err = s.GetOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, "Learn Go", todo.Task)
+	})
+
+	t.Run("requires a user", func(t *testing.T) {
+		s := newStore(t)
+		_, err := s.CreateOne(context.Background(), Todo{Task: "Learn Go"})
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+		_, err = s.GetAll(context.Background(), TodoQuery{})
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+	})
+
+	t.Run("fails with a cancelled context", func(t *testing.T) {
+		s := newStore(t)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := s.GetAll(cancelled, TodoQuery{})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func tasksOf(todos []Todo) []string {
+	tasks := make([]string, len(todos))
+	for i, todo := range todos {
+		tasks[i] = todo.Task
+	}
+	return tasks
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	b, err := json.Marshal(v)
+	assert.Nil(t, err)
+	return string(b)
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+)
+
+type TagStore interface {
+	GetTags(ctx context.Context) ([]Tag, error)
+}
+
+var (
+	_ TagStore = (*TagRepository)(nil)
+	_ TagStore = (*MemoryTagRepository)(nil)
+)
+
+type TagRepository struct {
+	db DB
+	options
+}
+
+func NewTagRepository(db DB, opts ...Option) *TagRepository {
+	return &TagRepository{db, newOptions(opts)}
+}
+
+func (r *TagRepository) GetTags(ctx context.Context) ([]Tag, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `--sql
+		select "tag",
+			   count(*)
+		  from "todo_tags"
+		  join "todos" using ("todoId")
+		 where "userId"    = $1
+		   and "deletedAt" is null
+		 group by "tag"
+		 order by "tag"
+	`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("querying database: %w", err)
+	}
+	defer rows.Close()
+	tags := make([]Tag, 0)
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+	return tags, nil
+}
+
+type MemoryTagRepository struct {
+	todos *MemoryTodoRepository
+}
+
+func NewMemoryTagRepository(todos *MemoryTodoRepository) *MemoryTagRepository {
+	return &MemoryTagRepository{todos}
+}
+
+func (r *MemoryTagRepository) GetTags(ctx context.Context) ([]Tag, error) {
+	counts := make(map[string]int)
+	err := r.todos.read(ctx, func(owner uuid.UUID) {
+		for _, todo := range r.todos.todos {
+			if todo.owner == owner && todo.deletedAt == nil {
+				for _, tag := range todo.Tags {
+					counts[tag]++
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, Tag{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
@@ -1,111 +1,572 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 	. "todo-app/data"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+type TodoStore interface {
+	CreateOne(ctx context.Context, t Todo) (*Todo, error)
+	UpdateOneByID(ctx context.Context, id uuid.UUID, t Todo, ifMatch *time.Time) (*Todo, error)
+	PatchOneByID(ctx context.Context, id uuid.UUID, p TodoPatch, ifMatch *time.Time) (*Todo, error)
+	DeleteOneByID(ctx context.Context, id uuid.UUID) (*Todo, error)
+	RestoreOneByID(ctx context.Context, id uuid.UUID) (*Todo, error)
+	MoveOneByID(ctx context.Context, id uuid.UUID, m TodoMove) (*Todo, error)
+	GetOneByID(ctx context.Context, id uuid.UUID) (*Todo, error)
+	GetAll(ctx context.Context, q TodoQuery) (*TodoPage, error)
+	GetHistory(ctx context.Context, id uuid.UUID) ([]TodoEvent, error)
+	RevertOneByID(ctx context.Context, id uuid.UUID, eventID int64) (*Todo, error)
+	Search(ctx context.Context, q TodoSearch) ([]TodoSearchResult, error)
+	Batch(ctx context.Context, ops []TodoOperation, atomic bool) ([]TodoOperationResult, error)
+}
+
+var (
+	_ TodoStore = (*TodoRepository)(nil)
+	_ TodoStore = (*MemoryTodoRepository)(nil)
+)
+
+type options struct {
+	softDelete   bool
+	queryTimeout time.Duration
+}
+
+type Option func(*options)
+
+func WithSoftDelete() Option {
+	return func(o *options) {
+		o.softDelete = true
+	}
+}
+
+func WithQueryTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.queryTimeout = d
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 type TodoRepository struct {
 	db DB
+	options
 }
 
-func NewTodoRepository(db DB) *TodoRepository {
-	return &TodoRepository{db}
+func NewTodoRepository(db DB, opts ...Option) *TodoRepository {
+	return &TodoRepository{db, newOptions(opts)}
 }
 
-func (r *TodoRepository) CreateOne(t Todo) (*Todo, error) {
-	var todo Todo
-	row := r.db.QueryRow(`--sql
-		insert into "todos" ("task", "isCompleted")
-		values ($1, $2)
-		returning "todoId",
-				  "task",
-				  "isCompleted",
-				  "createdAt",
-				  "updatedAt"
-	`, t.Task, t.IsCompleted)
-	err := row.Scan(&todo.ID, &todo.Task, &todo.IsCompleted, &todo.CreatedAt, &todo.UpdatedAt)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("scanning row: %w", err)
+func ownerOf(ctx context.Context) (uuid.UUID, error) {
+	owner, ok := UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, ErrUnauthenticated
 	}
-	return &todo, nil
+	return owner, nil
 }
 
-func (r *TodoRepository) UpdateOneByID(id uuid.UUID, t Todo) (*Todo, error) {
-	var todo Todo
-	row := r.db.QueryRow(`--sql
-		update "todos"
-		   set "task"        = coalesce($1, "task"),
-			   "isCompleted" = coalesce($2, "isCompleted"),
-			   "updatedAt"   = now()
-		 where "todoId"      = $3
-		returning "todoId",
-				  "task",
-				  "isCompleted",
-				  "createdAt",
-				  "updatedAt"
-	`, t.Task, t.IsCompleted, id)
-	err := row.Scan(&todo.ID, &todo.Task, &todo.IsCompleted, &todo.CreatedAt, &todo.UpdatedAt)
-	if err == sql.ErrNoRows {
-		return nil, nil
+func (o options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
 	}
-	if err != nil {
-		return nil, fmt.Errorf("scanning row: %w", err)
+	return context.WithTimeout(ctx, o.queryTimeout)
+}
+
+const todoColumns = `"todoId", "task", "isCompleted", "dueAt", "priority", "completedAt", "listId",
+	"parentTodoId", "position", "deriveCompletion", "createdAt", "updatedAt"`
+
+func tagsOf(table string) string {
+	return `coalesce((select array_agg("tag" order by "tag")
+				   from "todo_tags"
+				  where "todo_tags"."todoId" = ` + table + `."todoId"), '{}')`
+}
+
+func todoFields(todo *Todo) []any {
+	return []any{
+		&todo.ID, &todo.Task, &todo.IsCompleted, &todo.DueAt, &todo.Priority, &todo.CompletedAt, &todo.ListID,
+		&todo.ParentID, &todo.Position, &todo.DeriveCompletion, &todo.CreatedAt, &todo.UpdatedAt, pq.Array(&todo.Tags),
 	}
-	return &todo, nil
 }
 
-func (r *TodoRepository) GetOneByID(id uuid.UUID) (*Todo, error) {
+func scanTodo(row *sql.Row) (*Todo, error) {
 	var todo Todo
-	row := r.db.QueryRow(`--sql
-		select "todoId",
-			   "task",
-			   "isCompleted",
-			   "createdAt",
-			   "updatedAt"
-		  from "todos"
-		 where "todoId" = $1
-	`, id)
-	err := row.Scan(&todo.ID, &todo.Task, &todo.IsCompleted, &todo.CreatedAt, &todo.UpdatedAt)
+	err := row.Scan(todoFields(&todo)...)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
+	if err := constraintError(err); err != nil {
+		return nil, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("scanning row: %w", err)
 	}
 	return &todo, nil
 }
 
-func (r *TodoRepository) GetAll() ([]Todo, error) {
-	rows, err := r.db.Query(`--sql
-		select "todoId",
-			   "task",
-			   "isCompleted",
-			   "createdAt",
-			   "updatedAt"
-		  from "todos"
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("querying database: %w", err)
+func constraintError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != sqlStateForeignKeyViolation {
+		return nil
 	}
+	switch pqErr.Constraint {
+	case "todos_listId_fkey":
+		return ErrListNotFound
+	case "todos_parentTodoId_fkey":
+		return ErrParentNotFound
+	default:
+		return nil
+	}
+}
+
+func scanTodos(rows *sql.Rows) ([]Todo, error) {
 	all := make([]Todo, 0)
 	defer rows.Close()
 	for rows.Next() {
 		todo := Todo{}
-		err := rows.Scan(
-			&todo.ID, &todo.Task, &todo.IsCompleted, &todo.CreatedAt, &todo.UpdatedAt,
-		)
+		err := rows.Scan(todoFields(&todo)...)
 		if err != nil {
 			return nil, fmt.Errorf("scanning row: %w", err)
 		}
 		all = append(all, todo)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
 	return all, nil
 }
+
+func (r *TodoRepository) CreateOne(ctx context.Context, t Todo) (*Todo, error) {
+	return r.audited(ctx, EventCreated, nil, func(tx *TodoRepository) (*Todo, error) {
+		return tx.createOne(ctx, t)
+	})
+}
+
+func (r *TodoRepository) createOne(ctx context.Context, t Todo) (*Todo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := scanTodo(r.db.QueryRowContext(ctx, `--sql
+		with "todo" as (
+			insert into "todos" ("task", "isCompleted", "dueAt", "priority", "completedAt", "listId",
+								 "parentTodoId", "position", "deriveCompletion", "userId")
+			select $1::text, $2::boolean, $3::timestamptz, $4::smallint, case when $2 then now() end, $5::uuid,
+				   $8::uuid, coalesce((select max("position")
+										 from "todos"
+										where "userId"       = $6
+										  and "parentTodoId" is not distinct from $8::uuid), 0) + $10,
+				   $9::boolean, $6::uuid
+			 where $8::uuid is null
+				or exists (select 1
+							 from "todos"
+							where "todoId"    = $8
+							  and "userId"    = $6
+							  and "deletedAt" is null)
+			returning *
+		), "tags" as (
+			insert into "todo_tags" ("todoId", "tag")
+			select "todoId", unnest($7::text[])
+			  from "todo"
+		)
+		select `+todoColumns+`, $7::text[]
+		  from "todo"
+	`, t.Task, t.IsCompleted, t.DueAt, t.Priority, t.ListID, owner, pq.Array(NormalizeTags(t.Tags)),
+		t.ParentID, t.DeriveCompletion, PositionGap))
+	if todo == nil && err == nil {
+		return nil, ErrParentNotFound
+	}
+	return todo, err
+}
+
+func (r *TodoRepository) UpdateOneByID(ctx context.Context, id uuid.UUID, t Todo, ifMatch *time.Time) (*Todo, error) {
+	return r.audited(ctx, EventUpdated, &id, func(tx *TodoRepository) (*Todo, error) {
+		return tx.updateOneByID(ctx, id, t, ifMatch)
+	})
+}
+
+func (r *TodoRepository) updateOneByID(ctx context.Context, id uuid.UUID, t Todo, ifMatch *time.Time) (*Todo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkParentUnchanged(ctx, id, owner, t.ParentID); err != nil {
+		return nil, err
+	}
+	todo, err := scanTodo(r.db.QueryRowContext(ctx, `--sql
+		with "todo" as (
+			update "todos"
+			   set "task"        = $1,
+				   "isCompleted" = $2,
+				   "dueAt"       = $6,
+				   "priority"    = $7,
+				   "listId"      = $8,
+				   "deriveCompletion" = $10,
+				   "completedAt" = case when not $2 then null
+										when "isCompleted" then "completedAt"
+										else now() end,
+				   "updatedAt"   = clock_timestamp()
+			 where "todoId"      = $3
+			   and "deletedAt"   is null
+			   and "userId"      = $5
+			   and ($4::timestamptz is null or "updatedAt" = $4)
+			returning *
+		), "removed" as (
+			delete from "todo_tags"
+			 where "todoId" in (select "todoId" from "todo")
+			   and not ("tag" = any($9::text[]))
+		), "added" as (
+			insert into "todo_tags" ("todoId", "tag")
+			select "todoId", unnest($9::text[])
+			  from "todo"
+			    on conflict do nothing
+		)
+		select `+todoColumns+`, $9::text[]
+		  from "todo"
+	`, t.Task, t.IsCompleted, id, ifMatch, owner, t.DueAt, t.Priority, t.ListID, pq.Array(NormalizeTags(t.Tags)),
+		t.DeriveCompletion))
+	if todo == nil && err == nil && ifMatch != nil {
+		return nil, r.checkExists(ctx, id, owner)
+	}
+	return todo, err
+}
+
+func (r *TodoRepository) PatchOneByID(ctx context.Context, id uuid.UUID, p TodoPatch, ifMatch *time.Time) (*Todo, error) {
+	return r.audited(ctx, EventUpdated, &id, func(tx *TodoRepository) (*Todo, error) {
+		return tx.patchOneByID(ctx, id, p, ifMatch)
+	})
+}
+
+func (r *TodoRepository) patchOneByID(ctx context.Context, id uuid.UUID, p TodoPatch, ifMatch *time.Time) (*Todo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	if p.Tags != nil {
+		tags = NormalizeTags(*p.Tags)
+	}
+	todo, err := scanTodo(r.db.QueryRowContext(ctx, `--sql
+		with "todo" as (
+			update "todos"
+			   set "task"        = coalesce($1, "task"),
+				   "isCompleted" = coalesce($2, "isCompleted"),
+				   "dueAt"       = case when $6 then $7 else "dueAt" end,
+				   "priority"    = coalesce($8::smallint, "priority"),
+				   "listId"      = case when $9 then $10::uuid else "listId" end,
+				   "deriveCompletion" = coalesce($12, "deriveCompletion"),
+				   "completedAt" = case when $2::boolean is null then "completedAt"
+										when not $2 then null
+										when "isCompleted" then "completedAt"
+										else now() end,
+				   "updatedAt"   = clock_timestamp()
+			 where "todoId"      = $3
+			   and "deletedAt"   is null
+			   and "userId"      = $5
+			   and ($4::timestamptz is null or "updatedAt" = $4)
+			returning *
+		), "removed" as (
+			delete from "todo_tags"
+			 where "todoId" in (select "todoId" from "todo")
+			   and $11::text[] is not null
+			   and not ("tag" = any($11))
+		), "added" as (
+			insert into "todo_tags" ("todoId", "tag")
+			select "todoId", unnest($11::text[])
+			  from "todo"
+			    on conflict do nothing
+		)
+		select `+todoColumns+`, coalesce($11, `+tagsOf(`"todo"`)+`)
+		  from "todo"
+	`, p.Task, p.IsCompleted, id, ifMatch, owner, p.DueAt.Set, p.DueAt.Value, p.Priority,
+		p.ListID.Set, p.ListID.Value, pq.Array(tags), p.DeriveCompletion))
+	if todo == nil && err == nil && ifMatch != nil {
+		return nil, r.checkExists(ctx, id, owner)
+	}
+	return todo, err
+}
+
+// checkParentUnchanged refuses a replacement that reparents the todo, which
+// only a move can do because it renumbers siblings.
+func (r *TodoRepository) checkParentUnchanged(ctx context.Context, id uuid.UUID, owner uuid.UUID, parentID *string) error {
+	var changed bool
+	err := r.db.QueryRowContext(ctx, `--sql
+		select "parentTodoId" is distinct from $3::uuid
+		  from "todos"
+		 where "todoId"    = $1
+		   and "userId"    = $2
+		   and "deletedAt" is null
+	`, id, owner, parentID).Scan(&changed)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("scanning row: %w", err)
+	}
+	if changed {
+		return ErrParentChanged
+	}
+	return nil
+}
+
+func (r *TodoRepository) checkExists(ctx context.Context, id uuid.UUID, owner uuid.UUID) error {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `--sql
+		select exists (
+			select 1
+			  from "todos"
+			 where "todoId"    = $1
+			   and "userId"    = $2
+			   and "deletedAt" is null
+		)
+	`, id, owner).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("scanning row: %w", err)
+	}
+	if exists {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+func (r *TodoRepository) DeleteOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	return r.audited(ctx, EventDeleted, &id, func(tx *TodoRepository) (*Todo, error) {
+		return tx.deleteOneByID(ctx, id)
+	})
+}
+
+func (r *TodoRepository) deleteOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.softDelete {
+		return scanTodo(r.db.QueryRowContext(ctx, `--sql
+			with recursive "tree" as (
+				select "todoId"
+				  from "todos"
+				 where "todoId"    = $1
+				   and "userId"    = $2
+				   and "deletedAt" is null
+				 union all
+				select "todos"."todoId"
+				  from "todos"
+				  join "tree" on "todos"."parentTodoId" = "tree"."todoId"
+				 where "todos"."deletedAt" is null
+			), "deleted" as (
+				update "todos"
+				   set "deletedAt" = now()
+				 where "todoId" in (select "todoId" from "tree")
+				returning *
+			), "events" as (
+				insert into "todo_events" ("todoId", "actorId", "kind", "before", "after")
+				select "todoId", $2, $3, '{}', '{}'
+				  from "deleted"
+				 where "todoId" <> $1
+			)
+			select `+todoColumns+`, `+tagsOf(`"deleted"`)+`
+			  from "deleted"
+			 where "todoId" = $1
+		`, id, owner, EventDeleted))
+	}
+	return scanTodo(r.db.QueryRowContext(ctx, `--sql
+		with recursive "tree" as (
+			select "todoId"
+			  from "todos"
+			 where "todoId"    = $1
+			   and "userId"    = $2
+			   and "deletedAt" is null
+			 union all
+			select "todos"."todoId"
+			  from "todos"
+			  join "tree" on "todos"."parentTodoId" = "tree"."todoId"
+		), "events" as (
+			insert into "todo_events" ("todoId", "actorId", "kind", "before", "after")
+			select "todoId", $2, $3, '{}', '{}'
+			  from "tree"
+			 where "todoId" <> $1
+		)
+		delete from "todos"
+		 where "todoId"    = $1
+		   and "userId"    = $2
+		   and "deletedAt" is null
+		returning `+todoColumns+`, `+tagsOf(`"todos"`)+`
+	`, id, owner, EventDeleted))
+}
+
+func (r *TodoRepository) RestoreOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	return r.audited(ctx, EventRestored, &id, func(tx *TodoRepository) (*Todo, error) {
+		return tx.restoreOneByID(ctx, id)
+	})
+}
+
+func (r *TodoRepository) restoreOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return scanTodo(r.db.QueryRowContext(ctx, `--sql
+		with recursive "target" as (
+			select "todoId",
+				   "deletedAt"
+			  from "todos"
+			 where "todoId"    = $1
+			   and "userId"    = $2
+			   and "deletedAt" is not null
+		), "tree" as (
+			select "todoId"
+			  from "target"
+			 union all
+			select "todos"."todoId"
+			  from "todos"
+			  join "tree" on "todos"."parentTodoId" = "tree"."todoId"
+			 where "todos"."deletedAt" = (select "deletedAt" from "target")
+		), "restored" as (
+			update "todos"
+			   set "deletedAt" = null,
+				   "updatedAt" = clock_timestamp()
+			 where "todoId" in (select "todoId" from "tree")
+			returning *
+		), "events" as (
+			insert into "todo_events" ("todoId", "actorId", "kind", "before", "after")
+			select "todoId", $2, $3, '{}', '{}'
+			  from "restored"
+			 where "todoId" <> $1
+		)
+		select `+todoColumns+`, `+tagsOf(`"restored"`)+`
+		  from "restored"
+		 where "todoId" = $1
+	`, id, owner, EventRestored))
+}
+
+func (r *TodoRepository) GetOneByID(ctx context.Context, id uuid.UUID) (*Todo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return scanTodo(r.db.QueryRowContext(ctx, `--sql
+		select `+todoColumns+`, `+tagsOf(`"todos"`)+`
+		  from "todos"
+		 where "todoId"    = $1
+		   and "userId"    = $2
+		   and "deletedAt" is null
+	`, id, owner))
+}
+
+var todoSorts = map[TodoSort]struct {
+	column    string
+	direction string
+	operator  string
+}{
+	SortCreatedAt:     {`"createdAt"`, "asc", ">"},
+	SortCreatedAtDesc: {`"createdAt"`, "desc", "<"},
+	SortUpdatedAt:     {`"updatedAt"`, "asc", ">"},
+	SortUpdatedAtDesc: {`"updatedAt"`, "desc", "<"},
+	SortPriority:      {`"priority"`, "asc", ">"},
+	SortPriorityDesc:  {`"priority"`, "desc", "<"},
+	SortPosition:      {`"position"`, "asc", ">"},
+	SortPositionDesc:  {`"position"`, "desc", "<"},
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *TodoRepository) GetAll(ctx context.Context, q TodoQuery) (*TodoPage, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	owner, err := ownerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort, ok := todoSorts[q.Sort]
+	if !ok {
+		q.Sort, sort = SortCreatedAt, todoSorts[SortCreatedAt]
+	}
+	if q.Cursor != nil && q.Cursor.Sort != q.Sort {
+		return nil, ErrInvalidCursor
+	}
+	limit := q.Limit
+	if limit <= 0 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	where := []string{`"userId" = ` + arg(owner), `"deletedAt" is null`}
+	if q.IsCompleted != nil {
+		where = append(where, `"isCompleted" = `+arg(*q.IsCompleted))
+	}
+	if q.Overdue != nil {
+		overdue := `"dueAt" < now() and not "isCompleted"`
+		if !*q.Overdue {
+			overdue = `not coalesce(` + overdue + `, false)`
+		}
+		where = append(where, overdue)
+	}
+	if q.ListID != nil {
+		where = append(where, `"listId" = `+arg(*q.ListID))
+	}
+	if q.ParentID != nil {
+		where = append(where, `"parentTodoId" = `+arg(*q.ParentID))
+	}
+	if q.Tag != "" {
+		where = append(where, `exists (select 1
+						  from "todo_tags"
+						 where "todo_tags"."todoId" = "todos"."todoId"
+						   and "tag" = `+arg(NormalizeTag(q.Tag))+`)`)
+	}
+	if q.Search != "" {
+		where = append(where, `"task" ilike '%' || `+arg(likeEscaper.Replace(q.Search))+` || '%'`)
+	}
+	if q.Cursor != nil {
+		where = append(where, fmt.Sprintf(
+			`(%s, "todoId") %s (%s, %s)`,
+			sort.column, sort.operator, arg(q.Cursor.Key()), arg(q.Cursor.ID),
+		))
+	}
+	rows, err := r.db.QueryContext(ctx, `--sql
+		select `+todoColumns+`, `+tagsOf(`"todos"`)+`
+		  from "todos"
+		 where `+strings.Join(where, "\n\t\t   and ")+`
+		 order by `+sort.column+` `+sort.direction+`, "todoId" `+sort.direction+`
+		 limit `+arg(limit+1), args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying database: %w", err)
+	}
+	all, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+	page := &TodoPage{Todos: all}
+	if len(all) > limit {
+		page.Todos = all[:limit]
+		next := NewTodoCursor(q.Sort, page.Todos[limit-1]).Encode()
+		page.NextCursor = &next
+	}
+	return page, nil
+}
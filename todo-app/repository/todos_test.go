@@ -1,31 +1,65 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"testing"
+	"time"
 	. "todo-app/data"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
-func withRollback(t *testing.T, f func(*sql.Tx)) {
+func beginTx(t *testing.T) *sql.Tx {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
 	conn, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
 	assert.Nil(t, err)
-	defer conn.Close()
 	tx, err := conn.Begin()
 	assert.Nil(t, err)
-	defer tx.Rollback()
-	f(tx)
+	t.Cleanup(func() {
+		tx.Rollback()
+		conn.Close()
+	})
+	_, err = tx.Exec(`--sql
+		insert into "users" ("userId", "email", "passwordHash")
+		values ($1, 'ada@example.com', ''),
+			   ($2, 'grace@example.com', '')
+	`, testUserID, otherUserID)
+	assert.Nil(t, err)
+	return tx
+}
+
+func withRollback(t *testing.T, f func(*sql.Tx)) {
+	f(beginTx(t))
+}
+
+var (
+	testUserID  = uuid.New()
+	otherUserID = uuid.New()
+	ctx         = ContextWithUserID(context.Background(), testUserID)
+	otherCtx    = ContextWithUserID(context.Background(), otherUserID)
+)
+
+func TestQueryTimeoutCancelsQuery(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx, WithQueryTimeout(time.Nanosecond))
+		_, err := r.GetAll(ctx, TodoQuery{})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
 }
 
 func TestEmptyTableGetsNoRows(t *testing.T) {
 	withRollback(t, func(tx *sql.Tx) {
 		r := NewTodoRepository(tx)
-		todos, err := r.GetAll()
+		page, err := r.GetAll(ctx, TodoQuery{})
 		assert.Nil(t, err)
-		assert.Len(t, todos, 0)
+		assert.Len(t, page.Todos, 0)
+		assert.Nil(t, page.NextCursor)
 	})
 }
 
@@ -33,22 +67,72 @@ func TestPopulatedTableGetsAllRows(t *testing.T) {
 	withRollback(t, func(tx *sql.Tx) {
 		r := NewTodoRepository(tx)
 		_, err := tx.Exec(`--sql
-			insert into "todos" ("task")
-			values ('Learn Go'),
-				   ('Do a Barrel Roll'),
-				   ('Try a Somersault')
-		`)
+			insert into "todos" ("task", "userId")
+			values ('Learn Go', $1),
+				   ('Do a Barrel Roll', $1),
+				   ('Try a Somersault', $1)
+		`, testUserID)
+		assert.Nil(t, err)
+		page, err := r.GetAll(ctx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 3)
+	})
+}
+
+func TestPopulatedTablePaginatesRows(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx)
+		_, err := tx.Exec(`--sql
+			insert into "todos" ("task", "createdAt", "userId")
+			values ('Learn Go', now() - interval '3 minutes', $1),
+				   ('Do a Barrel Roll', now() - interval '2 minutes', $1),
+				   ('Try a Somersault', now() - interval '1 minute', $1)
+		`, testUserID)
+		assert.Nil(t, err)
+		page, err := r.GetAll(ctx, TodoQuery{Limit: 2, Sort: SortCreatedAtDesc})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 2)
+		assert.Equal(t, page.Todos[0].Task, "Try a Somersault")
+		assert.NotNil(t, page.NextCursor)
+		cursor, err := DecodeTodoCursor(*page.NextCursor)
+		assert.Nil(t, err)
+		page, err = r.GetAll(ctx, TodoQuery{Limit: 2, Sort: SortCreatedAtDesc, Cursor: cursor})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
+		assert.Equal(t, page.Todos[0].Task, "Learn Go")
+		assert.Nil(t, page.NextCursor)
+		_, err = r.GetAll(ctx, TodoQuery{Cursor: cursor})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestPopulatedTableFiltersRows(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx)
+		_, err := tx.Exec(`--sql
+			insert into "todos" ("task", "isCompleted", "userId")
+			values ('Learn Go', true, $1),
+				   ('Learn 100% of Go', false, $1),
+				   ('Try a Somersault', false, $1)
+		`, testUserID)
+		assert.Nil(t, err)
+		isCompleted := false
+		page, err := r.GetAll(ctx, TodoQuery{IsCompleted: &isCompleted})
 		assert.Nil(t, err)
-		todos, err := r.GetAll()
+		assert.Len(t, page.Todos, 2)
+		page, err = r.GetAll(ctx, TodoQuery{Search: "go"})
 		assert.Nil(t, err)
-		assert.Len(t, todos, 3)
+		assert.Len(t, page.Todos, 2)
+		page, err = r.GetAll(ctx, TodoQuery{Search: "100%"})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
 	})
 }
 
 func TestEmptyTableGetsOneNilTodo(t *testing.T) {
 	withRollback(t, func(tx *sql.Tx) {
 		r := NewTodoRepository(tx)
-		todo, err := r.GetOneByID(uuid.New())
+		todo, err := r.GetOneByID(ctx, uuid.New())
 		assert.Nil(t, err)
 		assert.Nil(t, todo)
 	})
@@ -59,11 +143,11 @@ func TestNonEmptyTableGetsOneTodo(t *testing.T) {
 		r := NewTodoRepository(tx)
 		id := uuid.New()
 		_, err := tx.Exec(`--sql
-			insert into "todos" ("todoId", "task")
-			values ($1, 'Learn Go')
-		`, id)
+			insert into "todos" ("todoId", "task", "userId")
+			values ($1, 'Learn Go', $2)
+		`, id, testUserID)
 		assert.Nil(t, err)
-		todo, err := r.GetOneByID(id)
+		todo, err := r.GetOneByID(ctx, id)
 		assert.Nil(t, err)
 		assert.Equal(t, todo.Task, "Learn Go")
 	})
@@ -72,7 +156,7 @@ func TestNonEmptyTableGetsOneTodo(t *testing.T) {
 func TestEmptyTableUpdatesOneNilTodo(t *testing.T) {
 	withRollback(t, func(tx *sql.Tx) {
 		r := NewTodoRepository(tx)
-		todo, err := r.UpdateOneByID(uuid.New(), Todo{Task: ""})
+		todo, err := r.UpdateOneByID(ctx, uuid.New(), Todo{Task: ""}, nil)
 		assert.Nil(t, err)
 		assert.Nil(t, todo)
 	})
@@ -83,21 +167,158 @@ func TestNonEmptyTableUpdatesOneTodo(t *testing.T) {
 		r := NewTodoRepository(tx)
 		id := uuid.New()
 		_, err := tx.Exec(`--sql
-			insert into "todos" ("todoId", "task")
-			values ($1, 'Learn Go')
-		`, id)
+			insert into "todos" ("todoId", "task", "userId")
+			values ($1, 'Learn Go', $2)
+		`, id, testUserID)
 		assert.Nil(t, err)
-		todo, err := r.UpdateOneByID(id, Todo{Task: "Accept Go"})
+		todo, err := r.UpdateOneByID(ctx, id, Todo{Task: "Accept Go"}, nil)
 		assert.Nil(t, err)
 		assert.Equal(t, todo.Task, "Accept Go")
 	})
 }
 
+func TestNonEmptyTableReplacesOneTodo(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx)
+		id := uuid.New()
+		_, err := tx.Exec(`--sql
+			insert into "todos" ("todoId", "task", "isCompleted", "userId")
+			values ($1, 'Learn Go', true, $2)
+		`, id, testUserID)
+		assert.Nil(t, err)
+		todo, err := r.UpdateOneByID(ctx, id, Todo{Task: "Accept Go"}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, todo.Task, "Accept Go")
+		assert.False(t, todo.IsCompleted)
+	})
+}
+
+func TestEmptyTablePatchesOneNilTodo(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx)
+		task := "Accept Go"
+		todo, err := r.PatchOneByID(ctx, uuid.New(), TodoPatch{Task: &task}, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+	})
+}
+
+func TestNonEmptyTablePatchesOnlySuppliedFields(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx)
+		id := uuid.New()
+		_, err := tx.Exec(`--sql
+			insert into "todos" ("todoId", "task", "isCompleted", "userId")
+			values ($1, 'Learn Go', true, $2)
+		`, id, testUserID)
+		assert.Nil(t, err)
+		task := "Accept Go"
+		todo, err := r.PatchOneByID(ctx, id, TodoPatch{Task: &task}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, todo.Task, "Accept Go")
+		assert.True(t, todo.IsCompleted)
+		isCompleted := false
+		todo, err = r.PatchOneByID(ctx, id, TodoPatch{IsCompleted: &isCompleted}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, todo.Task, "Accept Go")
+		assert.False(t, todo.IsCompleted)
+	})
+}
+
 func TestCreatesOneTodo(t *testing.T) {
 	withRollback(t, func(tx *sql.Tx) {
 		r := NewTodoRepository(tx)
-		todo, err := r.CreateOne(Todo{Task: "Learn Go"})
+		todo, err := r.CreateOne(ctx, Todo{Task: "Learn Go"})
 		assert.Nil(t, err)
 		assert.Equal(t, todo.Task, "Learn Go")
 	})
 }
+
+func TestEmptyTableDeletesOneNilTodo(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx)
+		todo, err := r.DeleteOneByID(ctx, uuid.New())
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+	})
+}
+
+func TestNonEmptyTableDeletesOneTodo(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx)
+		id := uuid.New()
+		_, err := tx.Exec(`--sql
+			insert into "todos" ("todoId", "task", "userId")
+			values ($1, 'Learn Go', $2)
+		`, id, testUserID)
+		assert.Nil(t, err)
+		todo, err := r.DeleteOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, todo.Task, "Learn Go")
+		var count int
+		err = tx.QueryRow(`select count(*) from "todos" where "todoId" = $1`, id).Scan(&count)
+		assert.Nil(t, err)
+		assert.Equal(t, count, 0)
+		restored, err := r.RestoreOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, restored)
+	})
+}
+
+func TestSoftDeletedTodoIsHiddenAndRestorable(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx, WithSoftDelete())
+		id := uuid.New()
+		_, err := tx.Exec(`--sql
+			insert into "todos" ("todoId", "task", "userId")
+			values ($1, 'Learn Go', $2)
+		`, id, testUserID)
+		assert.Nil(t, err)
+		deleted, err := r.DeleteOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, deleted.Task, "Learn Go")
+		todo, err := r.GetOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Nil(t, todo)
+		page, err := r.GetAll(ctx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 0)
+		restored, err := r.RestoreOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, restored.Task, "Learn Go")
+		todo, err = r.GetOneByID(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, todo.Task, "Learn Go")
+	})
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx)
+		oops := errors.New("oops!")
+		err := r.WithTx(ctx, func(repo *TodoRepository) error {
+			if _, err := repo.CreateOne(ctx, Todo{Task: "Learn Go"}); err != nil {
+				return err
+			}
+			return oops
+		})
+		assert.ErrorIs(t, err, oops)
+		page, err := r.GetAll(ctx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 0)
+	})
+}
+
+func TestWithTxCommits(t *testing.T) {
+	withRollback(t, func(tx *sql.Tx) {
+		r := NewTodoRepository(tx)
+		err := r.WithTx(ctx, func(repo *TodoRepository) error {
+			_, err := repo.CreateOne(ctx, Todo{Task: "Learn Go"})
+			return err
+		})
+		assert.Nil(t, err)
+		page, err := r.GetAll(ctx, TodoQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Todos, 1)
+	})
+}
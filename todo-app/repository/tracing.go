@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"time"
+	. "todo-app/data"
+	"tracing"
+
+	"github.com/google/uuid"
+)
+
+type tracedTodoStore struct {
+	store  TodoStore
+	tracer *tracing.Tracer
+}
+
+func NewTracedTodoStore(store TodoStore, tracer *tracing.Tracer) TodoStore {
+	return &tracedTodoStore{store, tracer}
+}
+
+func (s *tracedTodoStore) start(ctx context.Context, method string, statement string) (context.Context, *tracing.Span) {
+	ctx, span := s.tracer.Start(ctx, "TodoStore."+method, tracing.SpanKindInternal)
+	span.SetAttribute("db.sql.table", "todos")
+	span.SetAttribute("db.statement.name", statement)
+	return ctx, span
+}
+
+func end(span *tracing.Span, err error) {
+	span.RecordError(err)
+	span.End()
+}
+
+func (s *tracedTodoStore) CreateOne(ctx context.Context, t Todo) (_ *Todo, err error) {
+	ctx, span := s.start(ctx, "CreateOne", "insertTodo")
+	defer func() { end(span, err) }()
+	return s.store.CreateOne(ctx, t)
+}
+
+func (s *tracedTodoStore) UpdateOneByID(ctx context.Context, id uuid.UUID, t Todo, ifMatch *time.Time) (_ *Todo, err error) {
+	ctx, span := s.start(ctx, "UpdateOneByID", "updateTodo")
+	defer func() { end(span, err) }()
+	return s.store.UpdateOneByID(ctx, id, t, ifMatch)
+}
+
+func (s *tracedTodoStore) PatchOneByID(ctx context.Context, id uuid.UUID, p TodoPatch, ifMatch *time.Time) (_ *Todo, err error) {
+	ctx, span := s.start(ctx, "PatchOneByID", "patchTodo")
+	defer func() { end(span, err) }()
+	return s.store.PatchOneByID(ctx, id, p, ifMatch)
+}
+
+func (s *tracedTodoStore) DeleteOneByID(ctx context.Context, id uuid.UUID) (_ *Todo, err error) {
+	ctx, span := s.start(ctx, "DeleteOneByID", "deleteTodo")
+	defer func() { end(span, err) }()
+	return s.store.DeleteOneByID(ctx, id)
+}
+
+func (s *tracedTodoStore) RestoreOneByID(ctx context.Context, id uuid.UUID) (_ *Todo, err error) {
+	ctx, span := s.start(ctx, "RestoreOneByID", "restoreTodo")
+	defer func() { end(span, err) }()
+	return s.store.RestoreOneByID(ctx, id)
+}
+
+func (s *tracedTodoStore) MoveOneByID(ctx context.Context, id uuid.UUID, m TodoMove) (_ *Todo, err error) {
+	ctx, span := s.start(ctx, "MoveOneByID", "moveTodo")
+	defer func() { end(span, err) }()
+	return s.store.MoveOneByID(ctx, id, m)
+}
+
+func (s *tracedTodoStore) GetOneByID(ctx context.Context, id uuid.UUID) (_ *Todo, err error) {
+	ctx, span := s.start(ctx, "GetOneByID", "selectTodo")
+	defer func() { end(span, err) }()
+	return s.store.GetOneByID(ctx, id)
+}
+
+func (s *tracedTodoStore) GetAll(ctx context.Context, q TodoQuery) (_ *TodoPage, err error) {
+	ctx, span := s.start(ctx, "GetAll", "selectTodos")
+	defer func() { end(span, err) }()
+	return s.store.GetAll(ctx, q)
+}
+
+func (s *tracedTodoStore) Search(ctx context.Context, q TodoSearch) (_ []TodoSearchResult, err error) {
+	ctx, span := s.start(ctx, "Search", "searchTodos")
+	defer func() { end(span, err) }()
+	return s.store.Search(ctx, q)
+}
+
+func (s *tracedTodoStore) GetHistory(ctx context.Context, id uuid.UUID) (_ []TodoEvent, err error) {
+	ctx, span := s.start(ctx, "GetHistory", "selectTodoEvents")
+	defer func() { end(span, err) }()
+	return s.store.GetHistory(ctx, id)
+}
+
+func (s *tracedTodoStore) RevertOneByID(ctx context.Context, id uuid.UUID, eventID int64) (_ *Todo, err error) {
+	ctx, span := s.start(ctx, "RevertOneByID", "revertTodo")
+	defer func() { end(span, err) }()
+	return s.store.RevertOneByID(ctx, id, eventID)
+}
+
+func (s *tracedTodoStore) Batch(ctx context.Context, ops []TodoOperation, atomic bool) (_ []TodoOperationResult, err error) {
+	ctx, span := s.start(ctx, "Batch", "batchTodos")
+	defer func() { end(span, err) }()
+	return s.store.Batch(ctx, ops, atomic)
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	. "todo-app/data"
+	"tracing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type spanRecorder struct {
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(ctx context.Context, spans []tracing.SpanData) error {
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func TestTracedTodoStore(t *testing.T) {
+	r := &spanRecorder{}
+	tracer := tracing.NewTracer(r)
+	store := NewTracedTodoStore(NewMemoryTodoRepository(), tracer)
+	ctx, parent := tracer.Start(ContextWithUserID(context.Background(), uuid.New()), "parent", tracing.SpanKindServer)
+	_, err := store.CreateOne(ctx, Todo{Task: "trace me"})
+	assert.Nil(t, err)
+	_, err = store.GetAll(context.Background(), TodoQuery{})
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	parent.End()
+	tracer.Close()
+
+	assert.Len(t, r.spans, 3)
+	assert.Equal(t, "TodoStore.CreateOne", r.spans[0].Name)
+	assert.Equal(t, parent.Context().SpanID, r.spans[0].ParentID)
+	assert.Equal(t, "insertTodo", r.spans[0].Attributes["db.statement.name"])
+	assert.Empty(t, r.spans[0].Error)
+	assert.Equal(t, "TodoStore.GetAll", r.spans[1].Name)
+	assert.Equal(t, ErrUnauthenticated.Error(), r.spans[1].Error)
+}
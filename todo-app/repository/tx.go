@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	. "todo-app/data"
+)
+
+type TxOption func(*TxRunner)
+
+// Serializable is for transactions that read rows to decide how to write
+// others, such as renumbering siblings, where concurrent writers would race.
+func Serializable() TxOption {
+	return func(r *TxRunner) {
+		r.Options = &sql.TxOptions{Isolation: sql.LevelSerializable}
+	}
+}
+
+func (r *TodoRepository) WithTx(ctx context.Context, fn func(repo *TodoRepository) error, opts ...TxOption) error {
+	db, ok := r.db.(TxBeginner)
+	if !ok {
+		return r.inSavepoint(ctx, "tx", func() error {
+			return fn(r)
+		})
+	}
+	runner := NewTxRunner(db)
+	for _, opt := range opts {
+		opt(runner)
+	}
+	return runner.Run(ctx, func(tx DB) error {
+		return fn(&TodoRepository{tx, r.options})
+	})
+}
+
+func (r *TodoRepository) inSavepoint(ctx context.Context, name string, fn func() error) error {
+	if _, err := r.db.ExecContext(ctx, `savepoint "`+name+`"`); err != nil {
+		return fmt.Errorf("creating savepoint: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			r.db.ExecContext(ctx, `rollback to savepoint "`+name+`"`)
+			panic(p)
+		}
+	}()
+	if err := fn(); err != nil {
+		if _, rollbackErr := r.db.ExecContext(ctx, `rollback to savepoint "`+name+`"`); rollbackErr != nil {
+			return fmt.Errorf("rolling back to savepoint: %w", rollbackErr)
+		}
+		return err
+	}
+	if _, err := r.db.ExecContext(ctx, `release savepoint "`+name+`"`); err != nil {
+		return fmt.Errorf("releasing savepoint: %w", err)
+	}
+	return nil
+}
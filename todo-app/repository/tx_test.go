@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDB struct {
+	isolations []sql.IsolationLevel
+	commits    int
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) { return d, nil }
+func (d *fakeDB) Driver() driver.Driver                        { return nil }
+func (d *fakeDB) Prepare(string) (driver.Stmt, error)          { return nil, errors.New("not supported") }
+func (d *fakeDB) Close() error                                 { return nil }
+func (d *fakeDB) Begin() (driver.Tx, error)                    { return nil, errors.New("not supported") }
+func (d *fakeDB) Commit() error                                { d.commits++; return nil }
+func (d *fakeDB) Rollback() error                              { return nil }
+
+func (d *fakeDB) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	d.isolations = append(d.isolations, sql.IsolationLevel(opts.Isolation))
+	return d, nil
+}
+
+func TestWithTxRetriesSerializationFailures(t *testing.T) {
+	fake := &fakeDB{}
+	r := NewTodoRepository(sql.OpenDB(fake))
+	attempts := 0
+	err := r.WithTx(context.Background(), func(tx *TodoRepository) error {
+		attempts++
+		if attempts < 2 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	}, Serializable())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 1, fake.commits)
+	assert.Equal(t, []sql.IsolationLevel{sql.LevelSerializable, sql.LevelSerializable}, fake.isolations)
+}
+
+func TestWithTxKeepsTheDefaultIsolation(t *testing.T) {
+	fake := &fakeDB{}
+	r := NewTodoRepository(sql.OpenDB(fake))
+	err := r.WithTx(context.Background(), func(tx *TodoRepository) error {
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []sql.IsolationLevel{sql.LevelDefault}, fake.isolations)
+}
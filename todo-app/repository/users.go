@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	. "todo-app/data"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sqlStateUniqueViolation     = "23505"
+	sqlStateForeignKeyViolation = "23503"
+)
+
+type UserStore interface {
+	CreateUser(ctx context.Context, u User) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+}
+
+var (
+	_ UserStore = (*UserRepository)(nil)
+	_ UserStore = (*MemoryUserRepository)(nil)
+)
+
+type UserRepository struct {
+	db DB
+	options
+}
+
+func NewUserRepository(db DB, opts ...Option) *UserRepository {
+	return &UserRepository{db, newOptions(opts)}
+}
+
+func scanUser(row *sql.Row) (*User, error) {
+	var user User
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning row: %w", err)
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, u User) (*User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	user, err := scanUser(r.db.QueryRowContext(ctx, `--sql
+		insert into "users" ("email", "passwordHash")
+		values ($1, $2)
+		returning "userId",
+				  "email",
+				  "passwordHash",
+				  "createdAt"
+	`, u.Email, u.PasswordHash))
+	if SQLState(err) == sqlStateUniqueViolation {
+		return nil, ErrEmailTaken
+	}
+	return user, err
+}
+
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	return scanUser(r.db.QueryRowContext(ctx, `--sql
+		select "userId",
+			   "email",
+			   "passwordHash",
+			   "createdAt"
+		  from "users"
+		 where lower("email") = lower($1)
+	`, email))
+}
+
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]*User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[string]*User)}
+}
+
+func (r *MemoryUserRepository) CreateUser(ctx context.Context, u User) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := strings.ToLower(u.Email)
+	if _, ok := r.users[key]; ok {
+		return nil, ErrEmailTaken
+	}
+	user := User{
+		ID:           uuid.NewString(),
+		Email:        u.Email,
+		PasswordHash: u.PasswordHash,
+		CreatedAt:    now(),
+	}
+	r.users[key] = &user
+	created := user
+	return &created, nil
+}
+
+func (r *MemoryUserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.users[strings.ToLower(email)]
+	if !ok {
+		return nil, nil
+	}
+	found := *user
+	return &found, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"testing"
+	. "todo-app/data"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryUserStore(t *testing.T) {
+	testUserStore(t, func(t *testing.T) UserStore {
+		return NewMemoryUserRepository()
+	})
+}
+
+func TestPostgresUserStore(t *testing.T) {
+	testUserStore(t, func(t *testing.T) UserStore {
+		return NewUserRepository(beginTx(t))
+	})
+}
+
+func testUserStore(t *testing.T, newStore func(t *testing.T) UserStore) {
+	t.Run("creates and finds a user by email", func(t *testing.T) {
+		s := newStore(t)
+		created, err := s.CreateUser(ctx, User{Email: "Linus@example.com", PasswordHash: "hash"})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, created.ID)
+		assert.Equal(t, "Linus@example.com", created.Email)
+		found, err := s.GetUserByEmail(ctx, "linus@EXAMPLE.com")
+		assert.Nil(t, err)
+		assert.Equal(t, created.ID, found.ID)
+		assert.Equal(t, "hash", found.PasswordHash)
+	})
+
+	t.Run("gets nil for a missing user", func(t *testing.T) {
+		s := newStore(t)
+		found, err := s.GetUserByEmail(ctx, "nobody@example.com")
+		assert.Nil(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("rejects a duplicate email", func(t *testing.T) {
+		s := newStore(t)
+		_, err := s.CreateUser(ctx, User{Email: "linus@example.com", PasswordHash: "hash"})
+		assert.Nil(t, err)
+		_, err = s.CreateUser(ctx, User{Email: "LINUS@example.com", PasswordHash: "hash"})
+		assert.ErrorIs(t, err, ErrEmailTaken)
+	})
+}
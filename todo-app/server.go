@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+type ServerConfig struct {
+	ListenAddress   string        `env:"LISTEN_ADDRESS" default:":8080"`
+	ReadTimeout     time.Duration `env:"READ_TIMEOUT" default:"10s"`
+	WriteTimeout    time.Duration `env:"WRITE_TIMEOUT" default:"30s"`
+	IdleTimeout     time.Duration `env:"IDLE_TIMEOUT" default:"2m"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"15s"`
+}
+
+func newServer(handler http.Handler, config ServerConfig) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+}
+
+func serve(ctx context.Context, server *http.Server, listener net.Listener, timeout time.Duration, closers ...io.Closer) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		server.Close()
+	}
+	for _, closer := range closers {
+		err = errors.Join(err, closeWithin(ctx, closer))
+	}
+	return err
+}
+
+func closeWithin(ctx context.Context, closer io.Closer) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- closer.Close()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubCloser struct {
+	closed atomic.Bool
+	delay  time.Duration
+}
+
+func (c *stubCloser) Close() error {
+	time.Sleep(c.delay)
+	c.closed.Store(true)
+	return nil
+}
+
+func startServer(t *testing.T, timeout time.Duration, closer io.Closer) (string, chan struct{}, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}), ServerConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, server, listener, timeout, closer)
+	}()
+	go func() {
+		<-started
+		cancel()
+	}()
+	t.Cleanup(cancel)
+	return "http://" + listener.Addr().String(), release, done
+}
+
+func TestServeDrainsRequestsOnShutdown(t *testing.T) {
+	closer := &stubCloser{}
+	url, release, done := startServer(t, time.Second, closer)
+	responses := make(chan int, 1)
+	go func() {
+		res, err := http.Get(url)
+		assert.Nil(t, err)
+		res.Body.Close()
+		responses <- res.StatusCode
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	assert.Equal(t, http.StatusOK, <-responses)
+	assert.Nil(t, <-done)
+	assert.True(t, closer.closed.Load())
+}
+
+func TestServeGivesUpAfterShutdownTimeout(t *testing.T) {
+	closer := &stubCloser{delay: time.Second}
+	url, release, done := startServer(t, 50*time.Millisecond, closer)
+	defer close(release)
+	go http.Get(url)
+	assert.ErrorIs(t, <-done, context.DeadlineExceeded)
+	assert.False(t, closer.closed.Load())
+}
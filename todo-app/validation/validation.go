@@ -0,0 +1,218 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Field + " " + err.Detail
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type Validator interface {
+	Validate() Errors
+}
+
+var ErrEmptyBody = errors.New("request body must not be empty")
+
+func DecodeJSON(r io.Reader, v any) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading body: %w", err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ErrEmptyBody
+	}
+	var raw any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return err
+	}
+	t := reflect.TypeOf(v)
+	if !matchesShape(raw, t) {
+		return fmt.Errorf("request body must be %s", describe(t))
+	}
+	if errs := checkFields(body, t, ""); len(errs) > 0 {
+		return errs
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return Errors{{typeErr.Field, "must be " + describe(typeErr.Type)}}
+		}
+		return err
+	}
+	if validator, ok := v.(Validator); ok {
+		if errs := validator.Validate(); len(errs) > 0 {
+			return errs
+		}
+	}
+	return nil
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func matchesShape(raw any, t reflect.Type) bool {
+	t = indirect(t)
+	switch raw.(type) {
+	case map[string]any:
+		return t.Kind() == reflect.Struct || t.Kind() == reflect.Map
+	case []any:
+		return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+	case nil:
+		return true
+	default:
+		switch t.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+			return false
+		default:
+			return true
+		}
+	}
+}
+
+func checkFields(body json.RawMessage, t reflect.Type, path string) Errors {
+	t = indirect(t)
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(raw))
+		for key := range raw {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var errs Errors
+		for _, key := range keys {
+			field, known := fields[key]
+			switch {
+			case !known:
+				errs = append(errs, FieldError{join(path, key), "is not a recognized field"})
+			case field.readOnly:
+				errs = append(errs, FieldError{join(path, key), "is read-only"})
+			default:
+				errs = append(errs, checkFields(raw[key], field.typ, join(path, key))...)
+			}
+		}
+		return errs
+	case reflect.Slice, reflect.Array:
+		var raw []json.RawMessage
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil
+		}
+		var errs Errors
+		for i, item := range raw {
+			errs = append(errs, checkFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return errs
+	default:
+		return nil
+	}
+}
+
+type jsonField struct {
+	typ      reflect.Type
+	readOnly bool
+}
+
+func jsonFields(t reflect.Type) map[string]jsonField {
+	fields := make(map[string]jsonField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = jsonField{field.Type, field.Tag.Get("validate") == "readonly"}
+	}
+	return fields
+}
+
+func join(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func Nest(path string, errs Errors) Errors {
+	nested := make(Errors, len(errs))
+	for i, err := range errs {
+		nested[i] = FieldError{join(path, err.Field), err.Detail}
+	}
+	return nested
+}
+
+func describe(t reflect.Type) string {
+	switch indirect(t).Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+func Required(field string, value string) *FieldError {
+	if strings.TrimSpace(value) == "" {
+		return &FieldError{field, "must not be blank"}
+	}
+	return nil
+}
+
+func MaxLength(field string, value string, max int) *FieldError {
+	if utf8.RuneCountInString(value) > max {
+		return &FieldError{field, fmt.Sprintf("must be at most %d characters", max)}
+	}
+	return nil
+}
+
+func Collect(errs ...*FieldError) Errors {
+	var all Errors
+	for _, err := range errs {
+		if err != nil {
+			all = append(all, *err)
+		}
+	}
+	return all
+}
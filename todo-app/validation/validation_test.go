@@ -0,0 +1,94 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type widget struct {
+	ID    string `json:"widgetId" validate:"readonly"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+	Note  string `json:"-"`
+}
+
+func (w widget) Validate() Errors {
+	return Collect(
+		Required("name", w.Name),
+		MaxLength("name", w.Name, 5),
+	)
+}
+
+func decode(body string) (widget, error) {
+	var w widget
+	err := DecodeJSON(bytes.NewBufferString(body), &w)
+	return w, err
+}
+
+func TestDecodeJSONValid(t *testing.T) {
+	w, err := decode(`{"name":"gear","count":2}`)
+	assert.Nil(t, err)
+	assert.Equal(t, widget{Name: "gear", Count: 2}, w)
+}
+
+func TestDecodeJSONEmptyBody(t *testing.T) {
+	_, err := decode("  ")
+	assert.ErrorIs(t, err, ErrEmptyBody)
+}
+
+func TestDecodeJSONMalformed(t *testing.T) {
+	for _, body := range []string{`{"name":`, `[]`, `"gear"`} {
+		_, err := decode(body)
+		var errs Errors
+		assert.Error(t, err, body)
+		assert.False(t, errors.As(err, &errs), body)
+	}
+}
+
+func TestDecodeJSONUnknownAndReadOnlyFields(t *testing.T) {
+	_, err := decode(`{"widgetId":"1","name":"gear","color":"red","Note":"x"}`)
+	assert.Equal(t, Errors{
+		{"Note", "is not a recognized field"},
+		{"color", "is not a recognized field"},
+		{"widgetId", "is read-only"},
+	}, err)
+}
+
+func TestDecodeJSONTypeMismatch(t *testing.T) {
+	_, err := decode(`{"name":"gear","count":"two"}`)
+	assert.Equal(t, Errors{{"count", "must be a number"}}, err)
+}
+
+func TestDecodeJSONRunsValidator(t *testing.T) {
+	_, err := decode(`{"name":" "}`)
+	assert.Equal(t, Errors{{"name", "must not be blank"}}, err)
+	_, err = decode(`{"name":"` + strings.Repeat("é", 6) + `"}`)
+	assert.Equal(t, Errors{{"name", "must be at most 5 characters"}}, err)
+}
+
+type crate struct {
+	Widgets []widget `json:"widgets"`
+}
+
+func TestDecodeJSONNestedFields(t *testing.T) {
+	var c crate
+	err := DecodeJSON(bytes.NewBufferString(`{"widgets":[{"name":"gear"},{"widgetId":"1","size":3}]}`), &c)
+	assert.Equal(t, Errors{
+		{"widgets[1].size", "is not a recognized field"},
+		{"widgets[1].widgetId", "is read-only"},
+	}, err)
+}
+
+func TestNest(t *testing.T) {
+	errs := Nest("widgets[0]", Errors{{"name", "must not be blank"}})
+	assert.Equal(t, Errors{{"widgets[0].name", "must not be blank"}}, errs)
+}
+
+func TestErrorsMessage(t *testing.T) {
+	err := Errors{{"name", "must not be blank"}, {"count", "must be a number"}}
+	assert.Equal(t, "name must not be blank; count must be a number", err.Error())
+}
@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"fmt"
+	"os"
+)
+
+type Config struct {
+	Exporter string `env:"TRACE_EXPORTER" default:"none" usage:"where to export spans: none, stdout or otlp"`
+	Endpoint string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" default:"http://localhost:4318"`
+}
+
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case "", "none", "stdout", "otlp":
+		return nil
+	default:
+		return fmt.Errorf("TRACE_EXPORTER must be none, stdout or otlp, not %q", c.Exporter)
+	}
+}
+
+func (c Config) NewTracer(service string) (*Tracer, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	switch c.Exporter {
+	case "stdout":
+		return NewTracer(NewWriterExporter(os.Stdout)), nil
+	case "otlp":
+		return NewTracer(NewOTLPExporter(c.Endpoint, service)), nil
+	default:
+		return nil, nil
+	}
+}
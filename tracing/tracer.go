@@ -0,0 +1,235 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota + 1
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+type SpanData struct {
+	Name       string         `json:"name"`
+	Kind       SpanKind       `json:"kind"`
+	TraceID    TraceID        `json:"traceId"`
+	SpanID     SpanID         `json:"spanId"`
+	ParentID   SpanID         `json:"parentSpanId,omitempty"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+const (
+	DefaultBatchSize     = 512
+	DefaultFlushInterval = 5 * time.Second
+	DefaultExportTimeout = 10 * time.Second
+	queueSize            = 2048
+)
+
+type Tracer struct {
+	exporter      Exporter
+	BatchSize     int
+	FlushInterval time.Duration
+	ExportTimeout time.Duration
+	OnError       func(error)
+	queue         chan SpanData
+	mu            sync.RWMutex
+	closed        bool
+	done          chan struct{}
+	start         sync.Once
+	stop          sync.Once
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter:      exporter,
+		BatchSize:     DefaultBatchSize,
+		FlushInterval: DefaultFlushInterval,
+		ExportTimeout: DefaultExportTimeout,
+		OnError: func(err error) {
+			log.Printf("tracing: %v", err)
+		},
+		queue: make(chan SpanData, queueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.Context()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	t.start.Do(func() {
+		go t.run()
+	})
+	span := &Span{tracer: t, data: SpanData{
+		Name:    name,
+		Kind:    kind,
+		TraceID: newTraceID(),
+		SpanID:  newSpanID(),
+		Start:   time.Now(),
+	}, sampled: true}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		span.data.TraceID = parent.TraceID
+		span.data.ParentID = parent.SpanID
+		span.sampled = parent.Sampled
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *Tracer) record(data SpanData) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		// the tracer was closed while the span was open
+		return
+	}
+	select {
+	case t.queue <- data:
+	default:
+		t.OnError(fmt.Errorf("dropping span %q: queue is full", data.Name))
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.FlushInterval)
+	defer ticker.Stop()
+	var batch []SpanData
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), t.ExportTimeout)
+		defer cancel()
+		if err := t.exporter.Export(ctx, batch); err != nil {
+			t.OnError(fmt.Errorf("exporting %d spans: %w", len(batch), err))
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case data, ok := <-t.queue:
+			if !ok {
+				flush()
+				return
+			}
+			if batch = append(batch, data); len(batch) >= t.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (t *Tracer) Close() error {
+	if t == nil {
+		return nil
+	}
+	t.stop.Do(func() {
+		t.start.Do(func() {
+			go t.run()
+		})
+		t.mu.Lock()
+		t.closed = true
+		close(t.queue)
+		t.mu.Unlock()
+	})
+	<-t.done
+	return nil
+}
+
+type Span struct {
+	tracer  *Tracer
+	mu      sync.Mutex
+	data    SpanData
+	sampled bool
+	ended   bool
+}
+
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID, Sampled: s.sampled}
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]any)
+	}
+	s.data.Attributes[key] = value
+}
+
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Error = err.Error()
+	}
+}
+
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	if s.sampled {
+		s.tracer.record(data)
+	}
+}
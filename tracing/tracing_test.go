@@ -0,0 +1,202 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (r *recorder) Export(ctx context.Context, spans []SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, err := ParseTraceparent(invalid)
+		assert.ErrorIs(t, err, ErrInvalidTraceparent, invalid)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	r := &recorder{}
+	tracer := NewTracer(r)
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+	ctx, parent := tracer.Start(ctx, "parent", SpanKindServer)
+	_, child := tracer.Start(ctx, "child", SpanKindInternal)
+	child.SetAttribute("db.operation", "select")
+	child.RecordError(errors.New("boom"))
+	child.End()
+	parent.End()
+	parent.End()
+	assert.Nil(t, tracer.Close())
+
+	assert.Len(t, r.spans, 2)
+	assert.Equal(t, "child", r.spans[0].Name)
+	assert.Equal(t, remote.TraceID, r.spans[0].TraceID)
+	assert.Equal(t, parent.Context().SpanID, r.spans[0].ParentID)
+	assert.Equal(t, "boom", r.spans[0].Error)
+	assert.Equal(t, map[string]any{"db.operation": "select"}, r.spans[0].Attributes)
+	assert.Equal(t, remote.SpanID, r.spans[1].ParentID)
+}
+
+func TestTracerSkipsUnsampledTraces(t *testing.T) {
+	r := &recorder{}
+	tracer := NewTracer(r)
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "span", SpanKindServer)
+	span.End()
+	tracer.Close()
+	assert.Empty(t, r.spans)
+}
+
+func TestTracerDropsSpansEndedAfterClose(t *testing.T) {
+	r := &recorder{}
+	tracer := NewTracer(r)
+	_, span := tracer.Start(context.Background(), "span", SpanKindInternal)
+	assert.Nil(t, tracer.Close())
+	span.End()
+	assert.Empty(t, r.spans)
+}
+
+type stuckExporter struct{}
+
+func (stuckExporter) Export(ctx context.Context, spans []SpanData) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTracerTimesOutExports(t *testing.T) {
+	tracer := NewTracer(stuckExporter{})
+	tracer.ExportTimeout = time.Millisecond
+	var errs []error
+	tracer.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+	_, span := tracer.Start(context.Background(), "span", SpanKindInternal)
+	span.End()
+	assert.Nil(t, tracer.Close())
+	assert.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.DeadlineExceeded)
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "span", SpanKindInternal)
+	span.SetAttribute("key", "value")
+	span.End()
+	assert.Equal(t, context.Background(), ctx)
+	assert.Nil(t, tracer.Close())
+}
+
+func TestTransport(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(TraceparentHeader)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	r := &recorder{}
+	tracer := NewTracer(r)
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindInternal)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/posts/1", nil)
+	res, err := NewClient(tracer).Do(req)
+	assert.Nil(t, err)
+	res.Body.Close()
+	parent.End()
+	tracer.Close()
+
+	assert.Len(t, r.spans, 2)
+	client := r.spans[0]
+	sc, err := ParseTraceparent(traceparent)
+	assert.Nil(t, err)
+	assert.Equal(t, client.TraceID, sc.TraceID)
+	assert.Equal(t, client.SpanID, sc.SpanID)
+	assert.Equal(t, parent.Context().SpanID, client.ParentID)
+	assert.Equal(t, SpanKindClient, client.Kind)
+	assert.Equal(t, http.StatusBadGateway, client.Attributes["http.status_code"])
+	assert.Equal(t, "502 Bad Gateway", client.Error)
+	assert.Empty(t, req.Header.Get(TraceparentHeader))
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]any
+	var contentType string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		contentType = r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		assert.Nil(t, json.Unmarshal(data, &body))
+	}))
+	defer collector.Close()
+	tracer := NewTracer(NewOTLPExporter(collector.URL, "todo-app"))
+	_, span := tracer.Start(context.Background(), "GET /v1/todos", SpanKindServer)
+	span.SetAttribute("http.status_code", 200)
+	span.End()
+	tracer.Close()
+
+	assert.Equal(t, "application/json", contentType)
+	resource := body["resourceSpans"].([]any)[0].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"attributes": []any{map[string]any{"key": "service.name", "value": map[string]any{"stringValue": "todo-app"}}},
+	}, resource["resource"])
+	spans := resource["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	exported := spans[0].(map[string]any)
+	assert.Equal(t, span.Context().TraceID.String(), exported["traceId"])
+	assert.Equal(t, span.Context().SpanID.String(), exported["spanId"])
+	assert.NotContains(t, exported, "parentSpanId")
+	assert.Equal(t, "GET /v1/todos", exported["name"])
+	assert.Equal(t, float64(SpanKindServer), exported["kind"])
+	assert.Equal(t, []any{map[string]any{"key": "http.status_code", "value": map[string]any{"intValue": "200"}}}, exported["attributes"])
+}
+
+func TestOTLPExporterReportsCollectorErrors(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+	err := NewOTLPExporter(collector.URL, "todo-app").Export(context.Background(), []SpanData{{Name: "span"}})
+	assert.EqualError(t, err, "collector responded 503 Service Unavailable")
+}
+
+func TestConfig(t *testing.T) {
+	tracer, err := Config{Exporter: "none"}.NewTracer("todo-app")
+	assert.Nil(t, err)
+	assert.Nil(t, tracer)
+	tracer, err = Config{Exporter: "otlp", Endpoint: "http://collector:4318/"}.NewTracer("todo-app")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://collector:4318/v1/traces", tracer.exporter.(*OTLPExporter).URL)
+	_, err = Config{Exporter: "zipkin"}.NewTracer("todo-app")
+	assert.EqualError(t, err, `TRACE_EXPORTER must be none, stdout or otlp, not "zipkin"`)
+}
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Transport struct {
+	Base   http.RoundTripper
+	Tracer *Tracer
+}
+
+func NewTransport(base http.RoundTripper, tracer *Tracer) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base, tracer}
+}
+
+func NewClient(tracer *Tracer) *http.Client {
+	return &http.Client{Transport: NewTransport(nil, tracer)}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.Tracer.Start(req.Context(), "HTTP "+req.Method, SpanKindClient)
+	defer span.End()
+	req = req.Clone(ctx)
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		req.Header.Set(TraceparentHeader, sc.Traceparent())
+	}
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Redacted())
+	res, err := t.Base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", res.StatusCode)
+	if res.StatusCode >= 500 {
+		span.RecordError(errors.New(res.Status))
+	}
+	return res, nil
+}
@@ -1,29 +1,42 @@
 package main
 
 import (
+	"config"
 	"fmt"
 	"os"
+	"requestid"
 
 	"github.com/gin-gonic/gin"
 )
 
+func requestID(c *gin.Context) {
+	id := requestid.Resolve(c.GetHeader(requestid.Header))
+	c.Header(requestid.Header, id)
+	c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+	c.Next()
+}
+
 func createApp() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
+	r.Use(requestID)
 	r.GET("/hello", func(c *gin.Context) {
 		c.String(200, "world")
 	})
 	return r
 }
 
+type Config struct {
+	ListenAddress string `env:"LISTEN_ADDRESS" default:"0.0.0.0:8080"`
+}
+
 func main() {
-	a := func() string {
-		if a, ok := os.LookupEnv("LISTEN_ADDRESS"); ok {
-			return a
-		}
-		return "0.0.0.0:8080"
-	}()
+	var c Config
+	if err := config.Load(&c); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 	s := createApp()
-	err := s.Run(a)
+	err := s.Run(c.ListenAddress)
 	fmt.Fprintf(os.Stderr, "failed to start server: %s", err)
 }
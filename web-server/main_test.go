@@ -21,3 +21,26 @@ func TestHelloWorldRoute(t *testing.T) {
 	assert.Equal(t, 200, r.Code)
 	assert.Equal(t, "world", r.Body.String())
 }
+
+func TestRequestID(t *testing.T) {
+	app := createApp()
+
+	r := httptest.NewRecorder()
+
+	req, err := http.NewRequest("GET", "/hello", nil)
+	assert.Nil(t, err)
+	req.Header.Set("X-Request-ID", "abc-123")
+
+	app.ServeHTTP(r, req)
+
+	assert.Equal(t, "abc-123", r.Header().Get("X-Request-ID"))
+
+	r = httptest.NewRecorder()
+
+	req, err = http.NewRequest("GET", "/hello", nil)
+	assert.Nil(t, err)
+
+	app.ServeHTTP(r, req)
+
+	assert.Len(t, r.Header().Get("X-Request-ID"), 32)
+}
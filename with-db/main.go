@@ -1,21 +1,59 @@
 package main
 
 import (
+	"config"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"migrate"
 	"os"
+	"with-db/migrations"
 	. "with-db/todos"
 
 	_ "github.com/lib/pq"
 )
 
+// with-db shares its database with todo-app, so it keeps its own history.
+const migrationsTable = "withDbSchemaMigrations"
+
+type Config struct {
+	DatabaseURL string `env:"DATABASE_URL" required:"true"`
+	AutoMigrate bool   `env:"AUTO_MIGRATE"`
+}
+
 func main() {
-	db, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	var c Config
+	if err := config.Load(&c); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	db, err := sql.Open("postgres", c.DatabaseURL)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
 		os.Exit(1)
 	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		m, err := migrate.New(db, migrations.FS, migrate.WithTable(migrationsTable))
+		if err == nil {
+			err = m.Run(context.Background(), os.Args[2:], os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if c.AutoMigrate {
+		m, err := migrate.New(db, migrations.FS, migrate.WithTable(migrationsTable))
+		if err == nil {
+			_, err = m.Up(context.Background())
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to migrate database: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	t := Todo{Task: "Learn Go"}
 	var created Todo
 	err = db.QueryRow(`--sql
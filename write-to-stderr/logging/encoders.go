@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Field struct {
+	Key   string
+	Value any
+}
+
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field
+}
+
+type Encoder interface {
+	Encode(w io.Writer, e Entry) error
+}
+
+func value(v any) any {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(w io.Writer, e Entry) error {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeJSON(&buf, e.Time.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(&buf, e.Level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSON(&buf, e.Message)
+	for _, f := range e.Fields {
+		buf.WriteByte(',')
+		writeJSON(&buf, f.Key)
+		buf.WriteByte(':')
+		writeJSON(&buf, value(f.Value))
+	}
+	buf.WriteString("}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeJSON(buf *bytes.Buffer, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(data)
+}
+
+type TextEncoder struct{}
+
+func (TextEncoder) Encode(w io.Writer, e Entry) error {
+	var buf bytes.Buffer
+	buf.WriteString(e.Time.Format("2006-01-02T15:04:05.000Z07:00"))
+	buf.WriteByte(' ')
+	fmt.Fprintf(&buf, "%-5s", strings.ToUpper(e.Level.String()))
+	buf.WriteByte(' ')
+	buf.WriteString(e.Message)
+	for _, f := range e.Fields {
+		buf.WriteByte(' ')
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		buf.WriteString(quote(fmt.Sprint(value(f.Value))))
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
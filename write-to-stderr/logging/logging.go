@@ -0,0 +1,157 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type Logger struct {
+	mu      *sync.Mutex
+	stdout  io.Writer
+	stderr  io.Writer
+	level   Level
+	encoder Encoder
+	fields  []Field
+	now     func() time.Time
+}
+
+type Option func(*Logger)
+
+func WithLevel(level Level) Option {
+	return func(l *Logger) {
+		l.level = level
+	}
+}
+
+func WithEncoder(encoder Encoder) Option {
+	return func(l *Logger) {
+		l.encoder = encoder
+	}
+}
+
+func WithOutput(stdout io.Writer, stderr io.Writer) Option {
+	return func(l *Logger) {
+		l.stdout, l.stderr = stdout, stderr
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(l *Logger) {
+		l.now = now
+	}
+}
+
+func New(opts ...Option) *Logger {
+	l := &Logger{
+		mu:      &sync.Mutex{},
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+		level:   LevelInfo,
+		encoder: TextEncoder{},
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func fields(keyvals []any) []Field {
+	fields := make([]Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok || i+1 == len(keyvals) {
+			fields = append(fields, Field{"!BADKEY", keyvals[i]})
+			i--
+			continue
+		}
+		fields = append(fields, Field{key, keyvals[i+1]})
+	}
+	return fields
+}
+
+func (l *Logger) With(keyvals ...any) *Logger {
+	child := *l
+	child.fields = append(append([]Field{}, l.fields...), fields(keyvals)...)
+	return &child
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Log(level Level, msg string, keyvals ...any) {
+	if !l.Enabled(level) {
+		return
+	}
+	e := Entry{
+		Time:    l.now().UTC(),
+		Level:   level,
+		Message: msg,
+		Fields:  append(append([]Field{}, l.fields...), fields(keyvals)...),
+	}
+	w := l.stdout
+	if level >= LevelWarn {
+		w = l.stderr
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.encoder.Encode(w, e); err != nil {
+		fmt.Fprintf(os.Stderr, "logging: %v\n", err)
+	}
+}
+
+func (l *Logger) Debug(msg string, keyvals ...any) {
+	l.Log(LevelDebug, msg, keyvals...)
+}
+
+func (l *Logger) Info(msg string, keyvals ...any) {
+	l.Log(LevelInfo, msg, keyvals...)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...any) {
+	l.Log(LevelWarn, msg, keyvals...)
+}
+
+func (l *Logger) Error(msg string, keyvals ...any) {
+	l.Log(LevelError, msg, keyvals...)
+}
+
+type contextKey struct{}
+
+var Default = New()
+
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return Default
+}
+
+type Config struct {
+	Level  Level  `env:"LOG_LEVEL" default:"info" usage:"debug, info, warn or error"`
+	Format string `env:"LOG_FORMAT" default:"text" usage:"text or json"`
+}
+
+func (c Config) Validate() error {
+	if c.Format != "text" && c.Format != "json" {
+		return fmt.Errorf("LOG_FORMAT must be text or json, not %q", c.Format)
+	}
+	return nil
+}
+
+func (c Config) NewLogger() *Logger {
+	var encoder Encoder = TextEncoder{}
+	if c.Format == "json" {
+		encoder = JSONEncoder{}
+	}
+	return New(WithLevel(c.Level), WithEncoder(encoder))
+}
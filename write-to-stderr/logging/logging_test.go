@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func clock() time.Time {
+	return time.Date(2023, 5, 12, 11, 22, 51, 0, time.UTC)
+}
+
+func TestText(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	l := New(WithOutput(&stdout, &stderr), WithClock(clock))
+	l.With("service", "todo-app").Info("listening", "address", ":8080", "timeout", 5*time.Second)
+	l.Error("request failed", "err", errors.New("connection refused"), "status", 500)
+	l.Debug("hidden")
+	assert.Equal(t, "2023-05-12T11:22:51.000Z INFO  listening service=todo-app address=:8080 timeout=5s\n", stdout.String())
+	assert.Equal(t, "2023-05-12T11:22:51.000Z ERROR request failed err=\"connection refused\" status=500\n", stderr.String())
+}
+
+func TestJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	l := New(WithOutput(&stdout, &stderr), WithClock(clock), WithEncoder(JSONEncoder{}), WithLevel(LevelDebug))
+	l.Debug("query", "rows", 3, "sql", `select "task"`)
+	l.Warn("slow", 42)
+	assert.Equal(t, `{"time":"2023-05-12T11:22:51Z","level":"debug","msg":"query","rows":3,"sql":"select \"task\""}`+"\n", stdout.String())
+	assert.Equal(t, `{"time":"2023-05-12T11:22:51Z","level":"warn","msg":"slow","!BADKEY":42}`+"\n", stderr.String())
+}
+
+func TestWithDoesNotShareFields(t *testing.T) {
+	var stdout bytes.Buffer
+	l := New(WithOutput(&stdout, &stdout), WithClock(clock)).With("a", 1)
+	l.With("b", 2)
+	l.With("c", 3).Info("message")
+	assert.Equal(t, "2023-05-12T11:22:51.000Z INFO  message a=1 c=3\n", stdout.String())
+}
+
+func TestContext(t *testing.T) {
+	assert.Same(t, Default, FromContext(context.Background()))
+	l := New()
+	assert.Same(t, l, FromContext(NewContext(context.Background(), l)))
+}
+
+func TestLevels(t *testing.T) {
+	var level Level
+	assert.Nil(t, level.UnmarshalText([]byte("WARN")))
+	assert.Equal(t, LevelWarn, level)
+	assert.EqualError(t, level.UnmarshalText([]byte("loud")), `unknown log level "loud"`)
+	assert.True(t, New(WithLevel(LevelWarn)).Enabled(LevelError))
+	assert.False(t, New(WithLevel(LevelWarn)).Enabled(LevelInfo))
+}
+
+func TestConfig(t *testing.T) {
+	assert.Nil(t, Config{Format: "json"}.Validate())
+	assert.EqualError(t, Config{Format: "xml"}.Validate(), `LOG_FORMAT must be text or json, not "xml"`)
+	assert.Equal(t, JSONEncoder{}, Config{Level: LevelError, Format: "json"}.NewLogger().encoder)
+}
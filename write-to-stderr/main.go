@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"write-to-stderr/logging"
 )
 
 func directly(message string) {
@@ -25,4 +26,8 @@ func main() {
 	directly(fmt.Sprintf("the error message is %q\n", "d'oh!"))
 	l := createLogger()
 	l.Println("Your music's bad and you should feel bad!")
+	// the logging package sends warnings and errors to stderr and everything else to stdout
+	structured := logging.New().With("speaker", "Zoidberg")
+	structured.Info("hooray, I'm useful!")
+	structured.Error("your music is bad", "feeling", "bad")
 }